@@ -0,0 +1,131 @@
+package goswarm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type runScopeKey struct{}
+
+// runScope is the run a tool call belongs to, nested runs read their history and budget from it.
+type runScope struct {
+	state *runState
+	args  option.RunOptions
+}
+
+func withRunScope(ctx Context, state *runState, args option.RunOptions) Context {
+	if state.artifactScope == "" {
+		state.artifactScope = artifactScope(ctx, args)
+	}
+	return NewContext(context.WithValue(ctx, runScopeKey{}, &runScope{state, args}))
+}
+
+func getRunScope(ctx Context) *runScope {
+	scope, _ := ctx.Value(runScopeKey{}).(*runScope)
+	return scope
+}
+
+// agentToolToJSON describes an agent tool to the model, it takes the request as a single string.
+func agentToolToJSON(tool *types.AgentTool) openai.ChatCompletionToolParam {
+	desc := tool.Description
+	if desc == "" {
+		desc = fmt.Sprintf("Ask the %s agent and get its answer.", tool.Agent.Name)
+	}
+
+	return openai.ChatCompletionToolParam{
+		Type: openai.F(openai.ChatCompletionToolTypeFunction),
+		Function: openai.F(openai.FunctionDefinitionParam{
+			Name:        openai.String(tool.ToolName()),
+			Description: openai.String(desc),
+			Parameters: openai.F(openai.FunctionParameters{
+				"type": "object",
+				"properties": map[string]map[string]string{
+					"input": {"type": "string", "description": "The request for the agent."},
+				},
+				"required": []string{"input"},
+			}),
+		}),
+	}
+}
+
+// callAgentTool runs the agent of the tool in a nested run and returns its final answer.
+// The structured output of agents with an output type is returned as JSON.
+func (s *Swarm) callAgentTool(ctx Context, tool *types.AgentTool, args types.ContextVariables) types.Result {
+	input, _ := args["input"].(string)
+	scope := getRunScope(ctx)
+
+	budget := s.agentToolBudget(tool, scope)
+	if budget <= 0 {
+		return types.Result{Value: fmt.Sprintf("Error: the turn budget is used up, %s was not asked.", tool.Agent.Name)}
+	}
+
+	opts := []option.RunOption{
+		option.WithModel(tool.Agent.Model),
+		option.WithMaxTurns(budget),
+	}
+
+	messages := NewMessages(nil)
+	if scope != nil {
+		opts = append(opts, option.WithDebug(scope.args.Debug))
+		if tool.SeedHistory {
+			messages = append(messages, seedHistory(scope.state.history)...)
+		}
+	}
+	messages = append(messages, openai.UserMessage(input))
+
+	nested := NewContext(ctx)
+	switch tool.Variables {
+	case types.CopyVariables:
+		vars := types.ContextVariables{}
+		for k, v := range ctx.GetVariables() {
+			vars[k] = v
+		}
+		nested.SetVariables(vars)
+	case types.IsolateVariables:
+		nested.SetVariables(types.ContextVariables{})
+	}
+
+	resp := s.Run(nested, tool.Agent, messages, opts...)
+	if tool.ShareBudget && scope != nil {
+		// the turns of the nested run count against the caller
+		scope.state.nested += len(resp.Messages)
+	}
+	switch {
+	case resp.Error != nil:
+		return types.Result{Value: fmt.Sprintf("Error: %s failed: %v", tool.Agent.Name, resp.Error)}
+	case len(resp.PendingApprovals) > 0:
+		return types.Result{Value: fmt.Sprintf("Error: %s needs an approval that cannot be given in a nested run.", tool.Agent.Name)}
+	case len(resp.Messages) == 0:
+		return types.Result{Value: fmt.Sprintf("Error: %s gave no answer.", tool.Agent.Name)}
+	}
+
+	return types.Result{Value: finalText(resp)}
+}
+
+// agentToolBudget returns the number of turns the nested run may take, 0 or less when the shared
+// budget of the caller is used up.
+func (s *Swarm) agentToolBudget(tool *types.AgentTool, scope *runScope) int {
+	budget := option.DefRunOptions.MaxTurns
+	if tool.MaxTurns > 0 {
+		budget = tool.MaxTurns
+	}
+	if tool.ShareBudget && scope != nil {
+		budget = min(budget, scope.args.MaxTurns-scope.state.used())
+	}
+	return budget
+}
+
+// seedHistory returns the history of the caller without the tool calls that are still running.
+func seedHistory(msgs []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	blocks := history.Blocks(msgs)
+	if n := len(blocks); n > 0 && len(history.PendingToolCalls(blocks[n-1])) > 0 {
+		blocks = blocks[:n-1]
+	}
+	return history.Flatten(blocks)
+}
@@ -0,0 +1,110 @@
+package goswarm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestAgentTool(t *testing.T) {
+	var researcherRequests, writerRequests []fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if strings.HasPrefix(messageText(req.Messages[0]), "Research.") {
+			researcherRequests = append(researcherRequests, req)
+			return assistantReply("The answer is 42.")
+		}
+
+		writerRequests = append(writerRequests, req)
+		if len(writerRequests) == 1 {
+			return toolCallReply("call_1", "Researcher", `{"input": "Find the answer."}`)
+		}
+		return assistantReply("Done.")
+	})
+
+	researcher := goswarm.NewAgent(
+		option.WithAgentName("Researcher"),
+		option.WithAgentInstructions(func(ctx goswarm.Context) string {
+			return fmt.Sprintf("Research. secret=%v", ctx.GetVariable("secret", "none"))
+		}),
+	)
+	writer := goswarm.NewAgent(
+		option.WithAgentName("Writer"),
+		option.WithAgentTools(goswarm.NewAgentTool(researcher,
+			option.WithToolSeedHistory(true),
+			option.WithToolVariables(types.IsolateVariables),
+		)),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+	ctx.SetVariable("secret", "s3cr3t")
+
+	resp := client.Run(ctx, writer, goswarm.NewMessages(openai.UserMessage("Write about the answer.")))
+
+	if resp.Agent != writer {
+		t.Fatalf("expected the writer to stay in control, got %s", resp.Agent.Name)
+	}
+	if len(researcherRequests) != 1 {
+		t.Fatalf("expected one nested run, got %d", len(researcherRequests))
+	}
+
+	// system, seeded user message, request of the writer
+	nested := researcherRequests[0].Messages
+	if len(nested) != 3 || messageText(nested[1]) != "Write about the answer." || messageText(nested[2]) != "Find the answer." {
+		t.Fatalf("unexpected nested history: %+v", nested)
+	}
+	if messageText(nested[0]) != "Research. secret=none" {
+		t.Fatalf("expected isolated variables, got %q", messageText(nested[0]))
+	}
+
+	last := writerRequests[1].Messages
+	if messageText(last[len(last)-1]) != "The answer is 42." {
+		t.Fatalf("expected the answer as tool result, got %+v", last[len(last)-1])
+	}
+}
+
+func TestAgentToolShareBudget(t *testing.T) {
+	var researcherRequests, writerRequests []fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if strings.HasPrefix(messageText(req.Messages[0]), "Research.") {
+			researcherRequests = append(researcherRequests, req)
+			return assistantReply("The answer is 42.")
+		}
+
+		writerRequests = append(writerRequests, req)
+		return toolCallReply(fmt.Sprintf("call_%d", len(writerRequests)), "Researcher", `{"input": "Find the answer."}`)
+	})
+
+	researcher := goswarm.NewAgent(option.WithAgentName("Researcher"), option.WithAgentInstructions("Research."))
+	writer := goswarm.NewAgent(
+		option.WithAgentName("Writer"),
+		option.WithAgentTools(goswarm.NewAgentTool(researcher, option.WithToolShareBudget(true))),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+
+	// tool call, tool result and the answer of the nested run use up the budget
+	client.Run(ctx, writer, goswarm.NewMessages(openai.UserMessage("Write about the answer.")), option.WithMaxTurns(3))
+
+	if len(writerRequests) != 1 || len(researcherRequests) != 1 {
+		t.Fatalf("expected the nested turns to count against the caller, got %d writer and %d nested requests",
+			len(writerRequests), len(researcherRequests))
+	}
+
+	// the tool call uses up the budget, the nested run does not start
+	writerRequests, researcherRequests = nil, nil
+	resp := client.Run(ctx, writer, goswarm.NewMessages(openai.UserMessage("Write about the answer.")), option.WithMaxTurns(1))
+
+	if len(researcherRequests) != 0 || len(resp.Messages) != 2 || !strings.Contains(history.Text(resp.Messages[1]), "turn budget is used up") {
+		t.Fatalf("expected the exhausted budget to be reported, got %d nested requests and %+v", len(researcherRequests), resp.Messages)
+	}
+}
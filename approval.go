@@ -0,0 +1,176 @@
+package goswarm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// Approve creates a decision that lets the tool call run.
+func Approve(toolCallID string) types.ApprovalDecision {
+	return types.ApprovalDecision{ToolCallID: toolCallID, Approved: true}
+}
+
+// ApproveWithArguments creates a decision that lets the tool call run with edited arguments.
+func ApproveWithArguments(toolCallID string, args types.ContextVariables) types.ApprovalDecision {
+	return types.ApprovalDecision{ToolCallID: toolCallID, Approved: true, Arguments: args}
+}
+
+// Deny creates a decision that rejects the tool call. The reason is passed on to the model.
+func Deny(toolCallID string, reason string) types.ApprovalDecision {
+	return types.ApprovalDecision{ToolCallID: toolCallID, Reason: reason}
+}
+
+// ErrNoPendingToolCalls is returned by Continue when the messages do not end with unanswered tool calls.
+var ErrNoPendingToolCalls = errors.New("no pending tool calls to continue")
+
+// Continue resumes a run that paused for approval. The messages end with the tool calls the run
+// paused on, the decisions say which of them may run. Calls without a decision keep waiting and
+// are returned in resp.PendingApprovals again.
+func (s *Swarm) Continue(ctx Context, agent *types.Agent, messages []openai.ChatCompletionMessageParamUnion, decisions []types.ApprovalDecision, opts ...option.RunOption) *types.Response {
+	args := option.DefRunOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+
+	if args.Stream {
+		for response := range s.ContinueAndStream(ctx, agent, messages, decisions, opts...) {
+			if resp, ok := response.(*types.Response); ok {
+				return resp
+			}
+		}
+		return NewResponse(nil, agent)
+	}
+
+	ctx.GetVariables()
+	ctx = s.withRedaction(ctx)
+
+	state, err := continueState(agent, messages)
+	if err != nil {
+		return &types.Response{Agent: agent, Error: err}
+	}
+	args.Approvals = decisions
+	state.summary = args.HistorySummary
+
+	return s.runLoop(ctx, state, args, opts)
+}
+
+// ContinueAndStream is the streaming variant of Continue.
+func (s *Swarm) ContinueAndStream(ctx Context, agent *types.Agent, messages []openai.ChatCompletionMessageParamUnion, decisions []types.ApprovalDecision, opts ...option.RunOption) <-chan any {
+	args := option.DefRunOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+
+	ctx.GetVariables()
+	ctx = s.withRedaction(ctx)
+
+	state, err := continueState(agent, messages)
+	if err != nil {
+		responseChan := make(chan any, 1)
+		responseChan <- &types.Response{Agent: agent, Error: err}
+		close(responseChan)
+		return responseChan
+	}
+	args.Approvals = decisions
+	state.summary = args.HistorySummary
+
+	return s.runStream(ctx, state, args, opts)
+}
+
+// continueState builds the state of a run that paused on the unanswered tool calls of messages.
+func continueState(agent *types.Agent, messages []openai.ChatCompletionMessageParamUnion) (*runState, error) {
+	pending := history.PendingToolCalls(messages)
+	if len(pending) == 0 {
+		return nil, ErrNoPendingToolCalls
+	}
+
+	return &runState{
+		agent:   agent,
+		history: messages,
+		initLen: len(messages),
+		pending: pending,
+	}, nil
+}
+
+// runTools executes the pending tool calls of the run. Calls of functions that require approval
+// only run once a decision was passed to Continue or Resume, the undecided ones are returned.
+// The error tells why a handoff stopped the run.
+func (s *Swarm) runTools(ctx Context, state *runState, args option.RunOptions) ([]types.PendingApproval, error) {
+	gated := map[string]bool{}
+	for _, f := range state.agent.ApprovalRequired {
+		gated[functionName(f)] = true
+	}
+
+	decisions := map[string]types.ApprovalDecision{}
+	for _, decision := range args.Approvals {
+		decisions[decision.ToolCallID] = decision
+	}
+
+	var calls []openai.ChatCompletionMessageToolCall
+	var denied []openai.ChatCompletionMessageParamUnion
+	var waiting []types.PendingApproval
+	var held []openai.ChatCompletionMessageToolCall
+
+	for _, call := range state.pending {
+		// HandleToolCalls rejects the calls of functions that were not offered, they need no approval
+		if !gated[call.Function.Name] || !isOffered(ctx, call.Function.Name) {
+			calls = append(calls, call)
+			continue
+		}
+
+		decision, ok := decisions[call.ID]
+		switch {
+		case !ok:
+			var arguments types.ContextVariables
+			json.Unmarshal([]byte(call.Function.Arguments), &arguments)
+			waiting = append(waiting, types.PendingApproval{
+				ToolCallID: call.ID,
+				Name:       call.Function.Name,
+				Arguments:  arguments,
+			})
+			held = append(held, call)
+		case decision.Approved:
+			if decision.Arguments != nil {
+				data, err := json.Marshal(decision.Arguments)
+				if err != nil {
+					denied = append(denied, openai.ToolMessage(call.ID, fmt.Sprintf("Error: invalid arguments: %v", err)))
+					continue
+				}
+				call.Function.Arguments = string(data)
+			}
+			calls = append(calls, call)
+		default:
+			debugPrint(args.Debug, "Tool call %s to %s was denied.", call.ID, call.Function.Name)
+			denied = append(denied, openai.ToolMessage(call.ID, deniedMessage(decision)))
+		}
+	}
+
+	partialResponse := s.HandleToolCalls(ctx, calls, s.agentFunctions(state.agent), args.Debug)
+	partialResponse.Messages = s.limitOutputs(ctx, state.agent, calls, partialResponse.Messages, args.Debug)
+	// denied calls first, the results may end with a message carrying attachments
+	state.history = append(state.history, denied...)
+	state.history = append(state.history, partialResponse.Messages...)
+	// the undecided calls stay pending, so that a checkpoint can resume them
+	state.pending = held
+	if partialResponse.Agent != nil {
+		if err := s.enterAgent(ctx, state, calls, partialResponse.Agent, args); err != nil {
+			return waiting, err
+		}
+	}
+
+	return waiting, nil
+}
+
+func deniedMessage(decision types.ApprovalDecision) string {
+	if decision.Reason == "" {
+		return "Error: the user denied this tool call."
+	}
+	return "Error: the user denied this tool call: " + decision.Reason
+}
@@ -0,0 +1,132 @@
+package goswarm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type refundArgs struct {
+	OrderID string `desc:"order to refund" required:"true"`
+}
+
+var refunded []string
+
+func LookupOrder(ctx goswarm.Context, args refundArgs) string {
+	return "order " + args.OrderID + " was paid"
+}
+
+func ProcessRefund(ctx goswarm.Context, args refundArgs) string {
+	if !ctx.IsAnalyze() {
+		refunded = append(refunded, args.OrderID)
+	}
+	return "refunded"
+}
+
+func TestApprovalRequired(t *testing.T) {
+	var requests []fakeRequest
+	refunded = nil
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) > 1 {
+			return assistantReply("done")
+		}
+
+		names := map[string]string{}
+		for _, tool := range req.Tools {
+			name := tool["function"].(map[string]any)["name"].(string)
+			names[name[strings.LastIndex(name, "_")+1:]] = name
+		}
+		return map[string]any{
+			"role": "assistant",
+			"tool_calls": []any{
+				map[string]any{"id": "call_lookup", "type": "function",
+					"function": map[string]any{"name": names["LookupOrder"], "arguments": `{"OrderID":"A-1"}`}},
+				map[string]any{"id": "call_refund", "type": "function",
+					"function": map[string]any{"name": names["ProcessRefund"], "arguments": `{"OrderID":"A-1"}`}},
+			},
+		}
+	})
+
+	agent := goswarm.NewAgent(
+		option.WithAgentFunctions(LookupOrder, ProcessRefund),
+		option.WithAgentApprovalRequired(ProcessRefund),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+	messages := goswarm.NewMessages(openai.UserMessage("Refund A-1."))
+
+	resp := client.Run(ctx, agent, messages)
+
+	if len(resp.PendingApprovals) != 1 || resp.PendingApprovals[0].ToolCallID != "call_refund" {
+		t.Fatalf("expected the refund to wait for approval, got %+v", resp.PendingApprovals)
+	}
+	if resp.PendingApprovals[0].Arguments["OrderID"] != "A-1" {
+		t.Fatalf("expected decoded arguments, got %+v", resp.PendingApprovals[0].Arguments)
+	}
+	if len(refunded) != 0 || len(resp.Messages) != 2 {
+		t.Fatalf("expected only the lookup to run, got %d messages and refunds %v", len(resp.Messages), refunded)
+	}
+
+	paused := append(messages, resp.Messages...)
+
+	// a denial is reported to the model
+	resp = client.Continue(ctx, agent, paused, []types.ApprovalDecision{goswarm.Deny("call_refund", "needs a manager")})
+	if len(refunded) != 0 || len(resp.PendingApprovals) != 0 {
+		t.Fatalf("expected no refund, got %v", refunded)
+	}
+	last := requests[len(requests)-1].Messages
+	if text := messageText(last[len(last)-1]); !strings.Contains(text, "needs a manager") {
+		t.Fatalf("expected the denial reason as tool result, got %q", text)
+	}
+
+	// an approval with edited arguments runs the tool once
+	client.Continue(ctx, agent, paused, []types.ApprovalDecision{goswarm.ApproveWithArguments("call_refund", map[string]any{"OrderID": "A-2"})})
+	if len(refunded) != 1 || refunded[0] != "A-2" {
+		t.Fatalf("expected the edited refund to run, got %v", refunded)
+	}
+}
+
+func TestContinueIsExplicit(t *testing.T) {
+	var requests []fakeRequest
+	refunded = nil
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) > 1 {
+			return assistantReply("done")
+		}
+		return toolCallReply("call_refund", req.Tools[0]["function"].(map[string]any)["name"].(string), `{"OrderID":"A-1"}`)
+	})
+	agent := goswarm.NewAgent(option.WithAgentFunctions(ProcessRefund))
+
+	ctx := goswarm.NewContext(context.Background())
+	messages := goswarm.NewMessages(openai.UserMessage("Refund A-1."))
+
+	resp := client.Run(ctx, agent, messages, option.WithExecuteTools(false))
+	messages = append(messages, resp.Messages...)
+
+	// running the history again does not execute the unanswered calls
+	client.Run(ctx, agent, messages)
+	if len(refunded) != 0 {
+		t.Fatalf("expected Run to leave the tool calls alone, got refunds %v", refunded)
+	}
+
+	resp = client.Continue(ctx, agent, messages, nil)
+	if resp.Error != nil || len(refunded) != 1 {
+		t.Fatalf("expected Continue to run the tool call, got %v and refunds %v", resp.Error, refunded)
+	}
+
+	resp = client.Continue(ctx, agent, append(messages, resp.Messages...), nil)
+	if !errors.Is(resp.Error, goswarm.ErrNoPendingToolCalls) {
+		t.Fatalf("expected ErrNoPendingToolCalls, got %v", resp.Error)
+	}
+}
@@ -0,0 +1,45 @@
+// Package artifact stores large tool outputs that are replaced with a reference in the history.
+// The model reads them page by page through the read_artifact tool.
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Load when no artifact is stored under the ID.
+var ErrNotFound = errors.New("artifact: not found")
+
+// Artifact is the full output of a tool call.
+type Artifact struct {
+	Scope     string    `json:"scope"` // Run or session the artifact belongs to, only it can read the artifact
+	ID        string    `json:"id"`    // ID of the tool call that produced the output
+	Tool      string    `json:"tool"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists artifacts. The IDs are unique within a scope.
+type Store interface {
+	Load(ctx context.Context, scope, id string) (*Artifact, error)
+	Save(ctx context.Context, a *Artifact) error
+	Delete(ctx context.Context, scope, id string) error
+	// DeleteScope removes every artifact of the scope.
+	DeleteScope(ctx context.Context, scope string) error
+}
+
+// Marshal encodes the artifact as JSON.
+func Marshal(a *Artifact) ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// Unmarshal decodes an artifact encoded by Marshal.
+func Unmarshal(data []byte) (*Artifact, error) {
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
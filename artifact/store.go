@@ -0,0 +1,127 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore keeps artifacts in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	artifacts map[string]map[string][]byte // scope -> id -> artifact
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{artifacts: map[string]map[string][]byte{}}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, scope, id string) (*Artifact, error) {
+	s.mu.Lock()
+	data, ok := s.artifacts[scope][id]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return Unmarshal(data)
+}
+
+func (s *MemoryStore) Save(ctx context.Context, a *Artifact) error {
+	// stored encoded, so that the caller can't modify the saved state
+	data, err := Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.artifacts[a.Scope] == nil {
+		s.artifacts[a.Scope] = map[string][]byte{}
+	}
+	s.artifacts[a.Scope][a.ID] = data
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, scope, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.artifacts[scope], id)
+	return nil
+}
+
+func (s *MemoryStore) DeleteScope(ctx context.Context, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.artifacts, scope)
+	return nil
+}
+
+// FileStore keeps every artifact as a JSON file in a directory per scope.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore writing into dir, which is created when missing.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) scopeDir(scope string) string {
+	// the prefix keeps scopes like "" and ".." inside dir
+	return filepath.Join(s.dir, "scope-"+url.PathEscape(scope))
+}
+
+func (s *FileStore) path(scope, id string) string {
+	return filepath.Join(s.scopeDir(scope), url.PathEscape(id)+".json")
+}
+
+func (s *FileStore) Load(ctx context.Context, scope, id string) (*Artifact, error) {
+	data, err := os.ReadFile(s.path(scope, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data)
+}
+
+func (s *FileStore) Save(ctx context.Context, a *Artifact) error {
+	data, err := Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.scopeDir(a.Scope), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash never leaves a partial artifact behind
+	tmp := s.path(a.Scope, a.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(a.Scope, a.ID))
+}
+
+func (s *FileStore) Delete(ctx context.Context, scope, id string) error {
+	err := os.Remove(s.path(scope, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) DeleteScope(ctx context.Context, scope string) error {
+	return os.RemoveAll(s.scopeDir(scope))
+}
@@ -0,0 +1,112 @@
+package goswarm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chiwooi/go-swarm/checkpoint"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// Resume continues the run checkpointed under checkpointID with option.WithCheckpointID.
+// Completed turns are not sent to the model again: the pending tool calls are executed and the
+// loop goes on from there. Tool calls that were running when the process stopped run again.
+// A run that paused for approval continues with the decisions, calls without one keep waiting.
+// The context variables of ctx are replaced with the checkpointed ones.
+func (s *Swarm) Resume(ctx Context, checkpointID string, decisions []types.ApprovalDecision, opts ...option.RunOption) (*types.Response, error) {
+	store := s.options.CheckpointStore
+	if store == nil {
+		return nil, errors.New("no checkpoint store configured, use option.WithCheckpointStore")
+	}
+
+	cp, err := store.Load(ctx, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := s.lookupAgent(cp.AgentName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the map is updated in place, requests of earlier runs may still hold the context
+	vars := ctx.GetVariables()
+	clear(vars)
+	for k, v := range cp.Variables {
+		vars[k] = v
+	}
+	ctx = s.withRedaction(ctx)
+
+	if cp.Done {
+		resp := NewResponse(cp.Messages[cp.InitLen:], agent)
+		resp.Handoffs = cp.Handoffs
+		resp.Summary = cp.Summary
+		resp.ArtifactScope = cp.ArtifactScope
+		return resp, nil
+	}
+
+	// the checkpointed budget comes first, so that the caller can still override it
+	opts = append([]option.RunOption{option.WithMaxTurns(cp.MaxTurns), option.WithCheckpointID(cp.ID)}, opts...)
+
+	args := option.DefRunOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+	args.Approvals = decisions
+
+	state := &runState{
+		agent:     agent,
+		history:   cp.Messages,
+		initLen:   cp.InitLen,
+		turns:     cp.Turns,
+		pending:   cp.PendingToolCalls,
+		filtered:  cp.Filtered,
+		filterEnd: cp.FilterEnd,
+		handoffs:  cp.Handoffs,
+		pinned:    cp.Pinned,
+		nested:    cp.Nested,
+		offered:   cp.Offered,
+		summary:   cp.Summary,
+		// empty in checkpoints of older versions, the scope is derived from the ID again
+		artifactScope: cp.ArtifactScope,
+	}
+
+	return s.runLoop(ctx, state, args, opts), nil
+}
+
+// saveCheckpoint stores the state of the run when checkpointing is enabled for it.
+// A run that can't be checkpointed stops with the error.
+func (s *Swarm) saveCheckpoint(ctx Context, state *runState, args option.RunOptions) error {
+	store := s.options.CheckpointStore
+	if store == nil || args.CheckpointID == "" {
+		return nil
+	}
+
+	cp := &checkpoint.Checkpoint{
+		ID:               args.CheckpointID,
+		AgentName:        state.agent.Name,
+		Messages:         state.history,
+		InitLen:          state.initLen,
+		Variables:        ctx.GetVariables(),
+		PendingToolCalls: state.pending,
+		Filtered:         state.filtered,
+		FilterEnd:        state.filterEnd,
+		Handoffs:         state.handoffs,
+		Pinned:           state.pinned,
+		Nested:           state.nested,
+		Offered:          state.offered,
+		Summary:          state.summary,
+		ArtifactScope:    state.artifactScope,
+		Turns:            state.turns,
+		MaxTurns:         args.MaxTurns,
+		Done:             state.done,
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := store.Save(ctx, cp); err != nil {
+		return fmt.Errorf("save checkpoint %s: %w", args.CheckpointID, err)
+	}
+	return nil
+}
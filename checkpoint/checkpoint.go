@@ -0,0 +1,152 @@
+// Package checkpoint stores the state of a run between the steps of the agent loop,
+// so that Swarm.Resume can continue a run after the process restarted.
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// ErrNotFound is returned by Store.Load when no checkpoint is stored under the ID.
+var ErrNotFound = errors.New("checkpoint: not found")
+
+// Checkpoint is the state of the run loop after a completed step.
+type Checkpoint struct {
+	ID        string
+	AgentName string                                   // Name of the active agent, resolved from a types.Registry
+	Messages  []openai.ChatCompletionMessageParamUnion // Messages passed to Run followed by the messages of the run
+	InitLen   int                                      // Number of messages passed to Run
+	Variables types.ContextVariables
+	// Tool calls of the last assistant message that were not executed yet.
+	PendingToolCalls []openai.ChatCompletionMessageToolCall
+	// What the active agent sees of Messages[:FilterEnd] after a handoff with a filter, nil without one.
+	Filtered      []openai.ChatCompletionMessageParamUnion
+	FilterEnd     int
+	Handoffs      []types.HandoffEvent // Handoffs made so far, the handoff policy counts them
+	Pinned        bool                 // A violated handoff policy keeps the agent active
+	Turns         int                  // Number of model calls made so far
+	Nested        int                  // Messages of nested runs charged to the turn budget
+	Offered       []string             // Names of the functions offered with the last model call, nil before the first
+	Summary       types.HistorySummary // Running summary of the history
+	ArtifactScope string               // Where the tool outputs of the run are saved
+	MaxTurns      int
+	Done          bool // The run finished, resuming returns the stored response
+	UpdatedAt     time.Time
+}
+
+// Store persists checkpoints.
+type Store interface {
+	Load(ctx context.Context, id string) (*Checkpoint, error)
+	Save(ctx context.Context, cp *Checkpoint) error
+	Delete(ctx context.Context, id string) error
+}
+
+// record is the JSON representation of a checkpoint.
+type record struct {
+	ID               string                                 `json:"id"`
+	AgentName        string                                 `json:"agent"`
+	Messages         json.RawMessage                        `json:"messages"` // encoded with history.Marshal
+	InitLen          int                                    `json:"init_len"`
+	Variables        types.ContextVariables                 `json:"variables"`
+	PendingToolCalls []openai.ChatCompletionMessageToolCall `json:"pending_tool_calls,omitempty"`
+	Filtered         json.RawMessage                        `json:"filtered,omitempty"` // encoded with history.Marshal
+	FilterEnd        int                                    `json:"filter_end,omitempty"`
+	Handoffs         []types.HandoffEvent                   `json:"handoffs,omitempty"`
+	Pinned           bool                                   `json:"pinned,omitempty"`
+	Turns            int                                    `json:"turns"`
+	Nested           int                                    `json:"nested,omitempty"`
+	Offered          []string                               `json:"offered"` // an empty list offers nothing, null everything
+	Summary          types.HistorySummary                   `json:"summary"`
+	ArtifactScope    string                                 `json:"artifact_scope,omitempty"`
+	MaxTurns         int                                    `json:"max_turns"`
+	Done             bool                                   `json:"done"`
+	UpdatedAt        time.Time                              `json:"updated_at"`
+}
+
+// Marshal encodes the checkpoint as JSON.
+func Marshal(cp *Checkpoint) ([]byte, error) {
+	messages, err := history.Marshal(cp.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered json.RawMessage
+	if cp.Filtered != nil {
+		if filtered, err = history.Marshal(cp.Filtered); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(record{
+		ID:               cp.ID,
+		AgentName:        cp.AgentName,
+		Messages:         messages,
+		InitLen:          cp.InitLen,
+		Variables:        cp.Variables,
+		PendingToolCalls: cp.PendingToolCalls,
+		Filtered:         filtered,
+		FilterEnd:        cp.FilterEnd,
+		Handoffs:         cp.Handoffs,
+		Pinned:           cp.Pinned,
+		Nested:           cp.Nested,
+		Offered:          cp.Offered,
+		Summary:          cp.Summary,
+		ArtifactScope:    cp.ArtifactScope,
+		Turns:            cp.Turns,
+		MaxTurns:         cp.MaxTurns,
+		Done:             cp.Done,
+		UpdatedAt:        cp.UpdatedAt,
+	})
+}
+
+// Unmarshal decodes a checkpoint encoded by Marshal.
+func Unmarshal(data []byte) (*Checkpoint, error) {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+
+	messages, err := history.Unmarshal(rec.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []openai.ChatCompletionMessageParamUnion
+	if len(rec.Filtered) > 0 {
+		if filtered, err = history.Unmarshal(rec.Filtered); err != nil {
+			return nil, err
+		}
+	}
+
+	cp := &Checkpoint{
+		ID:               rec.ID,
+		AgentName:        rec.AgentName,
+		Messages:         messages,
+		InitLen:          rec.InitLen,
+		Variables:        rec.Variables,
+		PendingToolCalls: rec.PendingToolCalls,
+		Filtered:         filtered,
+		FilterEnd:        rec.FilterEnd,
+		Handoffs:         rec.Handoffs,
+		Pinned:           rec.Pinned,
+		Nested:           rec.Nested,
+		Offered:          rec.Offered,
+		Summary:          rec.Summary,
+		ArtifactScope:    rec.ArtifactScope,
+		Turns:            rec.Turns,
+		MaxTurns:         rec.MaxTurns,
+		Done:             rec.Done,
+		UpdatedAt:        rec.UpdatedAt,
+	}
+	if cp.Variables == nil {
+		cp.Variables = types.ContextVariables{}
+	}
+	return cp, nil
+}
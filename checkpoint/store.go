@@ -0,0 +1,104 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore keeps checkpoints in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu          sync.Mutex
+	checkpoints map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{checkpoints: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, id string) (*Checkpoint, error) {
+	s.mu.Lock()
+	data, ok := s.checkpoints[id]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return Unmarshal(data)
+}
+
+func (s *MemoryStore) Save(ctx context.Context, cp *Checkpoint) error {
+	// stored encoded, so that the caller can't modify the saved state
+	data, err := Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[cp.ID] = data
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkpoints, id)
+	return nil
+}
+
+// FileStore keeps every checkpoint as a JSON file in a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore writing into dir, which is created when missing.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
+
+func (s *FileStore) Load(ctx context.Context, id string) (*Checkpoint, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data)
+}
+
+func (s *FileStore) Save(ctx context.Context, cp *Checkpoint) error {
+	data, err := Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash never leaves a partial checkpoint behind
+	tmp := s.path(cp.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(cp.ID))
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
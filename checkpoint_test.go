@@ -0,0 +1,210 @@
+package goswarm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go"
+	oaioption "github.com/openai/openai-go/option"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/checkpoint"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestResume(t *testing.T) {
+	var requests []fakeRequest
+	calls := 0
+
+	lookup := func(ctx goswarm.Context) string {
+		if ctx.IsAnalyze() {
+			return ""
+		}
+		calls++
+		return "42"
+	}
+	agent := goswarm.NewAgent(option.WithAgentName("Agent"), option.WithAgentFunctions(lookup))
+
+	store := checkpoint.NewMemoryStore()
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return toolCallReply("call_1", req.Tools[0]["function"].(map[string]any)["name"].(string), "{}")
+		}
+		return assistantReply("The answer is 42.")
+	}, option.WithCheckpointStore(store), option.WithStartAgent(agent))
+
+	ctx := goswarm.NewContext(context.Background())
+	client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Look it up.")), option.WithCheckpointID("run-1"))
+
+	cp, err := store.Load(ctx, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cp.Done || cp.Turns != 2 || len(cp.Messages) != 4 {
+		t.Fatalf("unexpected checkpoint: %+v", cp)
+	}
+
+	// a finished run is not executed again
+	resp, err := client.Resume(ctx, "run-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(resp.Messages) != 3 {
+		t.Fatalf("expected the stored response, got %d messages after %d requests", len(resp.Messages), len(requests))
+	}
+
+	// rewind to the step after the first model call, as if the process stopped before the tool ran
+	cp.Messages = cp.Messages[:2]
+	cp.PendingToolCalls = history.ToolCalls(cp.Messages[1])
+	cp.Turns = 1
+	cp.Done = false
+	if err := store.Save(ctx, cp); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = client.Resume(ctx, "run-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the pending tool call to run, got %d calls", calls)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("expected a single model call after resuming, got %d", len(requests)-2)
+	}
+	if n := len(requests[2].Messages); n != 4 {
+		t.Fatalf("expected system, user, tool call and tool result, got %d messages", n)
+	}
+	if len(resp.Messages) != 3 {
+		t.Fatalf("expected tool call, tool result and answer, got %d messages", len(resp.Messages))
+	}
+}
+
+func TestResumeApproval(t *testing.T) {
+	var requests []fakeRequest
+	refunded = nil
+
+	agent := goswarm.NewAgent(
+		option.WithAgentName("Agent"),
+		option.WithAgentFunctions(ProcessRefund),
+		option.WithAgentApprovalRequired(ProcessRefund),
+	)
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) > 1 {
+			return assistantReply("done")
+		}
+		return toolCallReply("call_refund", req.Tools[0]["function"].(map[string]any)["name"].(string), `{"OrderID":"A-1"}`)
+	}, option.WithCheckpointStore(checkpoint.NewMemoryStore()), option.WithStartAgent(agent))
+
+	ctx := goswarm.NewContext(context.Background())
+	resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Refund A-1.")), option.WithCheckpointID("run-1"))
+	if len(resp.PendingApprovals) != 1 {
+		t.Fatalf("expected the refund to wait for approval, got %+v", resp.PendingApprovals)
+	}
+
+	// without a decision the call keeps waiting
+	resp, err := client.Resume(ctx, "run-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.PendingApprovals) != 1 || len(refunded) != 0 {
+		t.Fatalf("expected the refund to keep waiting, got %+v and refunds %v", resp.PendingApprovals, refunded)
+	}
+
+	resp, err = client.Resume(ctx, "run-1", []types.ApprovalDecision{goswarm.Approve("call_refund")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error != nil || len(resp.PendingApprovals) != 0 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(refunded) != 1 || len(requests) != 2 {
+		t.Fatalf("expected the approved refund and a single model call, got refunds %v after %d requests", refunded, len(requests))
+	}
+	if text := history.Text(resp.Messages[len(resp.Messages)-1]); text != "done" {
+		t.Fatalf("expected the final answer, got %q", text)
+	}
+}
+
+func TestCheckpointRunState(t *testing.T) {
+	cp := &checkpoint.Checkpoint{
+		ID:        "run-1",
+		Messages:  append(goswarm.NewMessages(openai.UserMessage("Hi.")), openai.AssistantMessage("Hello."), openai.UserMessage("Bye.")),
+		Filtered:  goswarm.NewMessages(openai.UserMessage("Summary.")),
+		FilterEnd: 2,
+		Handoffs:  []types.HandoffEvent{{From: "Triage", To: "Sales", Tool: "transfer_to_sales", ToolCallID: "call_1"}},
+		Pinned:    true,
+	}
+
+	data, err := checkpoint.Marshal(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := checkpoint.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Filtered) != 1 || history.Text(got.Filtered[0]) != "Summary." || got.FilterEnd != 2 {
+		t.Fatalf("expected the handoff filter to survive, got %d messages up to %d", len(got.Filtered), got.FilterEnd)
+	}
+	if len(got.Handoffs) != 1 || got.Handoffs[0] != cp.Handoffs[0] {
+		t.Fatalf("expected the handoffs to survive, got %+v", got.Handoffs)
+	}
+	if !got.Pinned {
+		t.Fatal("expected the pinned flag to survive")
+	}
+}
+
+// failingStore is a checkpoint store that can't save.
+type failingStore struct {
+	checkpoint.Store
+}
+
+func (failingStore) Save(ctx context.Context, cp *checkpoint.Checkpoint) error {
+	return errors.New("disk full")
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	agent := goswarm.NewAgent(option.WithAgentName("Agent"))
+	ctx := goswarm.NewContext(context.Background())
+
+	// a store that fails is reported on the response
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		return assistantReply("Hi.")
+	}, option.WithCheckpointStore(failingStore{}))
+
+	resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Hi!")), option.WithCheckpointID("run-1"))
+	if resp.Error == nil || resp.Error.Error() != "save checkpoint run-1: disk full" {
+		t.Fatalf("expected the checkpoint error, got %v", resp.Error)
+	}
+
+	// a streamed completion that fails ends the run with a response and a final checkpoint
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"message": "overloaded"}}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	store := checkpoint.NewMemoryStore()
+	client = goswarm.NewSwarm(openai.NewClient(
+		oaioption.WithBaseURL(srv.URL+"/"),
+		oaioption.WithAPIKey("test"),
+		oaioption.WithMaxRetries(0),
+	), option.WithCheckpointStore(store))
+
+	resp = client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Hi!")), option.WithStream(true), option.WithCheckpointID("run-2"))
+	if resp.Error == nil || resp.Agent != agent {
+		t.Fatalf("expected the completion error on the response, got %+v", resp)
+	}
+	if cp, err := store.Load(ctx, "run-2"); err != nil || !cp.Done {
+		t.Fatalf("expected a finished checkpoint, got %+v, %v", cp, err)
+	}
+}
@@ -10,10 +10,7 @@ import (
     "github.com/chiwooi/go-swarm/types"
 )
 
-func GetInstructions(ctx goswarm.Context) string {
-	name := ctx.GetVariable("name", "User")
-	return fmt.Sprintf("You are a helpful agent. Greet the user by name (%s).", name)
-}
+var instructions = goswarm.MustParseInstructions(`You are a helpful agent. Greet the user by name ({{or .Vars.name "User"}}).`)
 
 func PrintAccountDetails(ctx goswarm.Context) string {
 	if ctx.IsAnalyze() {
@@ -33,7 +30,7 @@ func main() {
 
 	agent := goswarm.NewAgent(
 		option.WithAgentModel("gpt-4o"),
-		option.WithAgentInstructions(GetInstructions),
+		option.WithAgentInstructions(instructions),
 		option.WithAgentFunctions(PrintAccountDetails),
 	)
 
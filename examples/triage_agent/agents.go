@@ -5,7 +5,6 @@ import (
 
     "github.com/chiwooi/go-swarm"
     "github.com/chiwooi/go-swarm/option"
-    "github.com/chiwooi/go-swarm/types"
 )
 
 type ProcessRefundArgs struct {
@@ -37,10 +36,6 @@ func ApplyDiscount(ctx goswarm.Context) string {
     return "Applied discount of 11%"
 }
 
-var triageAgent = goswarm.NewAgent(
-    option.WithAgentName("Triage Agent"), 
-    option.WithAgentInstructions("Determine which agent is best suited to handle the user's request, and transfer the conversation to that agent."),
-)
 var salesAgent = goswarm.NewAgent(
     option.WithAgentName("Sales Agent"), 
     option.WithAgentInstructions("Be super enthusiastic about selling bees."),
@@ -50,25 +45,17 @@ var refundsAgent = goswarm.NewAgent(
     option.WithAgentInstructions("Help the user with a refund. If the reason is that it was too expensive, offer the user a refund code. If they insist, then process the refund."),
     option.WithAgentFunctions(ProcessRefund, ApplyDiscount),
 )
-
-func transferBackToTriage(ctx goswarm.Context) *types.Agent {
-    if ctx.IsAnalyze() {
-        ctx.SetDescription("Call this function if a user is asking about a topic that is not handled by the current agent.")
-        return nil
-    }
-    return triageAgent
-}
-
-func transferToSales(ctx goswarm.Context) *types.Agent {
-    return salesAgent
-}
-
-func transferToRefunds(ctx goswarm.Context) *types.Agent {
-    return refundsAgent
-}
+var triageAgent = goswarm.NewAgent(
+    option.WithAgentName("Triage Agent"), 
+    option.WithAgentInstructions("Determine which agent is best suited to handle the user's request, and transfer the conversation to that agent."),
+    option.WithAgentHandoffs(salesAgent, refundsAgent),
+)
 
 func init() {
-    triageAgent.Functions = append(triageAgent.Functions, transferToSales, transferToRefunds)
+    // the way back is added once the triage agent exists
+    transferBackToTriage := goswarm.NewHandoff(triageAgent,
+        option.WithHandoffDescription("Call this function if a user is asking about a topic that is not handled by the current agent."),
+    )
     salesAgent.Functions = append(salesAgent.Functions, transferBackToTriage)
     refundsAgent.Functions = append(refundsAgent.Functions, transferBackToTriage)
 }
@@ -47,8 +47,8 @@ func TestTriageAgentCallsCorrectFunction(t *testing.T) {
         query string
         functionName string
     }{
-        {"I want to make a refund!", "transferToRefunds"},
-        {"I want to talk to sales.", "transferToSales"},
+        {"I want to make a refund!", "transfer_to_refunds_agent"},
+        {"I want to talk to sales.", "transfer_to_sales_agent"},
     }
 
     for _, data := range dataSet {
@@ -2,51 +2,33 @@ package main
 
 import (
     "context"
-    "encoding/json"
-    "fmt"
 
     "github.com/openai/openai-go"
+
+    "github.com/chiwooi/go-swarm"
+    "github.com/chiwooi/go-swarm/option"
 )
 
 // BoolEvalResult represents the structure of the response we expect from the API
 type BoolEvalResult struct {
-    Value  bool   `json:"value"`
-    Reason string `json:"reason,omitempty"`
+    Value  bool   `json:"value" desc:"true or false" required:"true"`
+    Reason string `json:"reason,omitempty" desc:"The reason for the evaluation"`
 }
 
 func evaluateWithLLMBool(instruction, data string) (*BoolEvalResult, error) {
-    // Set up the OpenAI client
-    client := openai.NewClient()
-
-    var messages []openai.ChatCompletionMessageParamUnion
-
-    // Added constraint statements to allow conversion of the result format to BoolEvalResult structure.
-    instruction += `
-
-Evaluate the following data and return a JSON object in the format:
-{
-    "value": true or false,
-    "reason": "The reason for the evaluation"
-}`
+    client := goswarm.NewSwarm(openai.NewClient())
 
-    messages = append(messages, openai.SystemMessage(instruction))
-    messages = append(messages, openai.UserMessage(data))
+    // The answer is requested as JSON with the schema of BoolEvalResult and decoded by RunTyped.
+    evaluator := goswarm.NewAgent(
+        option.WithAgentName("Evaluator"),
+        option.WithAgentInstructions(instruction),
+    )
 
-    // Create the chat completion request
-    req := openai.ChatCompletionNewParams{
-        Model:   openai.F("gpt-4o"),
-        Messages: openai.F(messages),
-    }
-
-    // Make the API call
-    resp, err := client.Chat.Completions.New(context.Background(), req)
+    ctx := goswarm.NewContext(context.Background())
+    result, _, err := goswarm.RunTyped[BoolEvalResult](client, ctx, evaluator, goswarm.NewMessages(openai.UserMessage(data)))
     if err != nil {
-        return nil, fmt.Errorf("failed to get chat completion: %v", err)
+        return nil, err
     }
 
-    // Assuming the response contains a message with the boolean value
-    var result BoolEvalResult
-    err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result)
-
     return &result, nil
 }
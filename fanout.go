@@ -0,0 +1,130 @@
+package goswarm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+const aggregatePrompt = `Several agents answered the conversation above. Combine their answers into a single answer.
+
+%s`
+
+// FanOut runs the agents concurrently on the same messages and combines their final answers with
+// the reducer or the aggregator agent of the options. Every branch works on a copy of the context
+// variables. The result holds every branch, also when the fan-out fails once the branches ran.
+func (s *Swarm) FanOut(ctx Context, agents []*types.Agent, messages []openai.ChatCompletionMessageParamUnion, opts ...option.FanOutOption) (*types.FanOutResult, error) {
+	args := option.DefFanOutOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+
+	if args.Policy == types.Quorum && (args.Quorum < 1 || args.Quorum > len(agents)) {
+		return nil, fmt.Errorf("quorum %d is not between 1 and the %d agents", args.Quorum, len(agents))
+	}
+
+	parent, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &types.FanOutResult{Branches: make([]types.BranchResult, len(agents))}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i, agent := range agents {
+		vars := types.ContextVariables{}
+		for k, v := range ctx.GetVariables() {
+			vars[k] = v
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			branch := s.runBranch(parent, vars, agent, messages, args)
+			result.Branches[i] = branch
+
+			if branch.Err != nil {
+				once.Do(func() { firstErr = fmt.Errorf("branch %s: %w", agent.Name, branch.Err) })
+				if args.Policy == types.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var succeeded []types.BranchResult
+	for _, branch := range result.Branches {
+		if branch.Err == nil {
+			succeeded = append(succeeded, branch)
+		}
+	}
+
+	switch {
+	case args.Policy == types.FailFast && firstErr != nil:
+		return result, firstErr
+	case args.Policy == types.BestEffort && len(succeeded) == 0 && len(agents) > 0:
+		return result, fmt.Errorf("all branches failed: %w", firstErr)
+	case args.Policy == types.Quorum && len(succeeded) < args.Quorum:
+		return result, fmt.Errorf("%d of %d branches succeeded, the quorum is %d: %w", len(succeeded), len(agents), args.Quorum, firstErr)
+	}
+
+	switch {
+	case args.Reducer != nil:
+		output, err := args.Reducer(succeeded)
+		if err != nil {
+			return result, err
+		}
+		result.Output = output
+	case args.Aggregator != nil:
+		var sb strings.Builder
+		for _, branch := range succeeded {
+			fmt.Fprintf(&sb, "## %s\n%s\n\n", branch.Agent.Name, finalText(branch.Response))
+		}
+
+		msgs := append(slices.Clip(messages), openai.UserMessage(fmt.Sprintf(aggregatePrompt, sb.String())))
+		resp := s.Run(ctx, args.Aggregator, msgs, args.RunOptions...)
+		result.Response = resp
+		if resp.Error != nil {
+			return result, fmt.Errorf("aggregator %s: %w", args.Aggregator.Name, resp.Error)
+		}
+		result.Output = finalText(resp)
+	}
+
+	return result, nil
+}
+
+// runBranch runs one agent of a fan-out.
+func (s *Swarm) runBranch(parent context.Context, vars types.ContextVariables, agent *types.Agent, messages []openai.ChatCompletionMessageParamUnion, args option.FanOutOptions) types.BranchResult {
+	start := time.Now()
+
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		parent, cancel = context.WithTimeout(parent, args.Timeout)
+		defer cancel()
+	}
+
+	ctx := NewContext(parent)
+	ctx.SetVariables(vars)
+
+	// the branches share the caller's messages, clipped so that none of them appends into the
+	// spare capacity of the others
+	resp := s.Run(ctx, agent, slices.Clip(messages), args.RunOptions...)
+
+	return types.BranchResult{
+		Agent:    agent,
+		Response: resp,
+		Err:      runError(resp),
+		Duration: time.Since(start),
+	}
+}
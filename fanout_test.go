@@ -0,0 +1,130 @@
+package goswarm_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestFanOut(t *testing.T) {
+	var mu sync.Mutex
+	var aggregated string
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		switch name := messageText(req.Messages[0]); name {
+		case "Slow":
+			time.Sleep(200 * time.Millisecond)
+			return assistantReply("too late")
+		case "Aggregate":
+			mu.Lock()
+			aggregated = messageText(req.Messages[len(req.Messages)-1])
+			mu.Unlock()
+			return assistantReply("combined")
+		default:
+			return assistantReply(name + " says hi")
+		}
+	})
+
+	agents := []*types.Agent{
+		goswarm.NewAgent(option.WithAgentName("Legal"), option.WithAgentInstructions("Legal")),
+		goswarm.NewAgent(option.WithAgentName("Finance"), option.WithAgentInstructions("Finance")),
+		goswarm.NewAgent(option.WithAgentName("Slow"), option.WithAgentInstructions("Slow")),
+	}
+	messages := goswarm.NewMessages(openai.UserMessage("Should we sign?"))
+	ctx := goswarm.NewContext(context.Background())
+
+	joinAnswers := func(results []types.BranchResult) (string, error) {
+		var answers []string
+		for _, r := range results {
+			answers = append(answers, r.Response.Messages[0].(openai.ChatCompletionMessage).Content)
+		}
+		sort.Strings(answers)
+		return strings.Join(answers, "; "), nil
+	}
+
+	t.Run("best effort", func(t *testing.T) {
+		result, err := client.FanOut(ctx, agents, messages,
+			option.WithBranchTimeout(50*time.Millisecond),
+			option.WithFailurePolicy(types.BestEffort),
+			option.WithReducer(joinAnswers),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Output != "Finance says hi; Legal says hi" {
+			t.Fatalf("unexpected output: %q", result.Output)
+		}
+		if result.Branches[2].Err == nil {
+			t.Fatal("expected the slow branch to time out")
+		}
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		_, err := client.FanOut(ctx, agents, messages, option.WithBranchTimeout(50*time.Millisecond))
+		if err == nil || !strings.Contains(err.Error(), "Slow") {
+			t.Fatalf("expected the slow branch to fail the fan-out, got %v", err)
+		}
+	})
+
+	t.Run("quorum", func(t *testing.T) {
+		_, err := client.FanOut(ctx, agents, messages, option.WithBranchTimeout(50*time.Millisecond), option.WithQuorum(3))
+		if err == nil {
+			t.Fatal("expected the quorum to be missed")
+		}
+
+		// a quorum above the number of agents is rejected before any branch runs
+		result, err := client.FanOut(ctx, agents, messages, option.WithQuorum(4))
+		if err == nil || result != nil || strings.Contains(err.Error(), "%!") {
+			t.Fatalf("expected the quorum to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("aggregator", func(t *testing.T) {
+		result, err := client.FanOut(ctx, agents[:2], messages,
+			option.WithAggregator(goswarm.NewAgent(option.WithAgentInstructions("Aggregate"))),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Output != "combined" || result.Response == nil {
+			t.Fatalf("unexpected aggregation: %+v", result)
+		}
+		if !strings.Contains(aggregated, "## Legal\nLegal says hi") || !strings.Contains(aggregated, "## Finance\nFinance says hi") {
+			t.Fatalf("aggregator did not get the answers: %q", aggregated)
+		}
+	})
+}
+
+func TestFanOutSpareCapacity(t *testing.T) {
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		return assistantReply(messageText(req.Messages[0]) + " says hi")
+	})
+
+	var agents []*types.Agent
+	for _, name := range []string{"A", "B", "C", "D", "E", "F"} {
+		agents = append(agents, goswarm.NewAgent(option.WithAgentName(name), option.WithAgentInstructions(name)))
+	}
+	// the branches must not append into the spare capacity of the caller's slice
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, 16)
+	messages = append(messages, openai.UserMessage("Should we sign?"))
+
+	result, err := client.FanOut(goswarm.NewContext(context.Background()), agents, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, branch := range result.Branches {
+		content := branch.Response.Messages[len(branch.Response.Messages)-1].(openai.ChatCompletionMessage).Content
+		if want := branch.Agent.Name + " says hi"; content != want {
+			t.Fatalf("branch %s returned %q", branch.Agent.Name, content)
+		}
+	}
+}
@@ -2,11 +2,17 @@ module github.com/chiwooi/go-swarm
 
 go 1.23.1
 
-require github.com/openai/openai-go v0.1.0-alpha.32
+require (
+	github.com/dlclark/regexp2 v1.11.4
+	github.com/openai/openai-go v0.1.0-alpha.32
+	go.etcd.io/bbolt v1.3.11
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/tidwall/gjson v1.14.4 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
+	golang.org/x/sys v0.22.0 // indirect
 )
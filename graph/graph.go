@@ -0,0 +1,154 @@
+// Package graph describes the network of agents connected by declarative handoffs and agent tools.
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// EdgeKind tells how one agent reaches another.
+type EdgeKind int
+
+const (
+	HandoffEdge EdgeKind = iota // The target becomes the active agent
+	ToolEdge                    // The target runs as a tool, the caller stays active
+)
+
+// Edge connects an agent to an agent it can hand off to or call as a tool.
+type Edge struct {
+	From *types.Agent
+	To   *types.Agent
+	Tool string // Name of the tool the model calls
+	Kind EdgeKind
+}
+
+// Graph holds the agents reachable from the root agent. Handoffs written as functions that
+// return an agent cannot be inspected and are not part of the graph.
+type Graph struct {
+	Root   *types.Agent
+	Agents []*types.Agent // In the order they are reached, the root first
+	Edges  []Edge
+}
+
+// New walks the handoffs and agent tools reachable from the root agent.
+func New(root *types.Agent) *Graph {
+	g := &Graph{Root: root}
+	seen := map[*types.Agent]bool{root: true}
+	queue := []*types.Agent{root}
+
+	for len(queue) > 0 {
+		agent := queue[0]
+		queue = queue[1:]
+		g.Agents = append(g.Agents, agent)
+
+		for _, edge := range edges(agent) {
+			g.Edges = append(g.Edges, edge)
+			if !seen[edge.To] {
+				seen[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+
+	return g
+}
+
+func edges(agent *types.Agent) []Edge {
+	var out []Edge
+	for _, f := range agent.Functions {
+		switch v := f.(type) {
+		case *types.Handoff:
+			out = append(out, Edge{From: agent, To: v.Agent, Tool: v.ToolName(), Kind: HandoffEdge})
+		case *types.AgentTool:
+			out = append(out, Edge{From: agent, To: v.Agent, Tool: v.ToolName(), Kind: ToolEdge})
+		}
+	}
+	return out
+}
+
+// Reachable reports whether the agent can be reached from the root.
+func (g *Graph) Reachable(agent *types.Agent) bool {
+	return g.index(agent) >= 0
+}
+
+// Unreachable returns the agents that cannot be reached from the root, e.g. the agents of a registry.
+func (g *Graph) Unreachable(agents ...*types.Agent) []*types.Agent {
+	var out []*types.Agent
+	for _, agent := range agents {
+		if !g.Reachable(agent) {
+			out = append(out, agent)
+		}
+	}
+	return out
+}
+
+// DeadEnds returns the agents the conversation can be handed to but that cannot hand it on.
+// Agents reached only as tools are left out, their caller stays active.
+func (g *Graph) DeadEnds() []*types.Agent {
+	active := map[*types.Agent]bool{g.Root: true}
+	leaves := map[*types.Agent]bool{}
+	for _, agent := range g.Agents {
+		leaves[agent] = true
+	}
+	for _, edge := range g.Edges {
+		if edge.Kind == HandoffEdge {
+			active[edge.To] = true
+			leaves[edge.From] = false
+		}
+	}
+
+	var out []*types.Agent
+	for _, agent := range g.Agents {
+		if active[agent] && leaves[agent] {
+			out = append(out, agent)
+		}
+	}
+	return out
+}
+
+func (g *Graph) index(agent *types.Agent) int {
+	for i, a := range g.Agents {
+		if a == agent {
+			return i
+		}
+	}
+	return -1
+}
+
+// DOT exports the graph in the Graphviz DOT language, agent tools are drawn dashed.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", strconv.Quote(g.Root.Name))
+	for i, agent := range g.Agents {
+		fmt.Fprintf(&b, "  a%d [label=%s];\n", i, strconv.Quote(agent.Name))
+	}
+	for _, edge := range g.Edges {
+		style := ""
+		if edge.Kind == ToolEdge {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  a%d -> a%d [label=%s%s];\n", g.index(edge.From), g.index(edge.To), strconv.Quote(edge.Tool), style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid exports the graph as a Mermaid flowchart, agent tools are drawn dotted.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, agent := range g.Agents {
+		fmt.Fprintf(&b, "  a%d[\"%s\"]\n", i, strings.ReplaceAll(agent.Name, `"`, "#quot;"))
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if edge.Kind == ToolEdge {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  a%d %s|%s| a%d\n", g.index(edge.From), arrow, edge.Tool, g.index(edge.To))
+	}
+	return b.String()
+}
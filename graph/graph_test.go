@@ -0,0 +1,47 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chiwooi/go-swarm/graph"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestGraph(t *testing.T) {
+	sales := &types.Agent{Name: "Sales"}
+	researcher := &types.Agent{Name: "Researcher"}
+	refunds := &types.Agent{Name: "Refunds", Functions: []types.AgentFunction{&types.AgentTool{Agent: researcher}}}
+	triage := &types.Agent{Name: "Triage", Functions: []types.AgentFunction{
+		&types.Handoff{Agent: sales},
+		&types.Handoff{Agent: refunds},
+	}}
+	refunds.Functions = append(refunds.Functions, &types.Handoff{Agent: triage})
+	orphan := &types.Agent{Name: "Orphan"}
+
+	g := graph.New(triage)
+
+	if len(g.Agents) != 4 || len(g.Edges) != 4 {
+		t.Fatalf("unexpected graph: %d agents, %d edges", len(g.Agents), len(g.Edges))
+	}
+	if unreachable := g.Unreachable(sales, orphan); len(unreachable) != 1 || unreachable[0] != orphan {
+		t.Fatalf("expected the orphan to be unreachable, got %+v", unreachable)
+	}
+	if deadEnds := g.DeadEnds(); len(deadEnds) != 1 || deadEnds[0] != sales {
+		t.Fatalf("expected sales to be the only dead end, got %+v", deadEnds)
+	}
+
+	dot := g.DOT()
+	for _, want := range []string{`digraph "Triage" {`, `a0 -> a1 [label="transfer_to_sales"];`, `a2 -> a3 [label="Researcher", style=dashed];`} {
+		if !strings.Contains(dot, want) {
+			t.Fatalf("expected %q in\n%s", want, dot)
+		}
+	}
+
+	mermaid := g.Mermaid()
+	for _, want := range []string{"flowchart LR", `a2["Refunds"]`, "a2 -->|transfer_to_triage| a0", "a2 -.->|Researcher| a3"} {
+		if !strings.Contains(mermaid, want) {
+			t.Fatalf("expected %q in\n%s", want, mermaid)
+		}
+	}
+}
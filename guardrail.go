@@ -0,0 +1,118 @@
+package goswarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// checkInput runs the input guardrails of the agent on the user messages at the end of the history.
+// Guardrails that are not concurrent run right away, the returned function waits for the others.
+func (s *Swarm) checkInput(ctx Context, agent *types.Agent, msgs []openai.ChatCompletionMessageParamUnion, args option.RunOptions) (func() error, error) {
+	input := inputText(msgs)
+	if input == "" || len(agent.InputGuardrails) == 0 {
+		return nil, nil
+	}
+
+	var concurrent []types.Guardrail
+	for _, guardrail := range agent.InputGuardrails {
+		if guardrail.Concurrent {
+			concurrent = append(concurrent, guardrail)
+			continue
+		}
+		if err := s.runGuardrail(ctx, guardrail, input, false, args.Debug); err != nil {
+			return nil, err
+		}
+	}
+	if len(concurrent) == 0 {
+		return nil, nil
+	}
+
+	errs := make(chan error, len(concurrent))
+	for _, guardrail := range concurrent {
+		go func() {
+			errs <- s.runGuardrail(ctx, guardrail, input, false, args.Debug)
+		}()
+	}
+
+	return func() error {
+		var first error
+		for range concurrent {
+			if err := <-errs; err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}, nil
+}
+
+// checkOutput runs the output guardrails of the agent on the final assistant content.
+func (s *Swarm) checkOutput(ctx Context, agent *types.Agent, output string, args option.RunOptions) error {
+	for _, guardrail := range agent.OutputGuardrails {
+		if err := s.runGuardrail(ctx, guardrail, output, true, args.Debug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGuardrail returns a *types.GuardrailError when the guardrail trips.
+// A guardrail that cannot give a verdict trips as well.
+func (s *Swarm) runGuardrail(ctx Context, guardrail types.Guardrail, text string, output bool, debug bool) error {
+	var result types.GuardrailResult
+
+	if guardrail.Agent != nil {
+		result = s.classify(ctx, guardrail.Agent, text, debug)
+	} else {
+		switch check := guardrail.Check.(type) {
+		case func(Context, string) types.GuardrailResult:
+			result = check(ctx, text)
+		default:
+			result = types.GuardrailResult{Tripwire: true, Reasoning: fmt.Sprintf("invalid check type: %T", guardrail.Check)}
+		}
+	}
+
+	if !result.Tripwire {
+		return nil
+	}
+
+	err := &types.GuardrailError{Guardrail: guardrail.Name, Output: output, Reasoning: result.Reasoning}
+	debugPrint(debug, "%v", err)
+	return err
+}
+
+// classify runs the classifier agent of a guardrail on the text and decodes its verdict.
+func (s *Swarm) classify(ctx Context, agent *types.Agent, text string, debug bool) types.GuardrailResult {
+	resp := s.Run(ctx, agent, NewMessages(openai.UserMessage(text)),
+		option.WithModel(agent.Model),
+		option.WithMaxTurns(1),
+		option.WithExecuteTools(false),
+		option.WithDebug(debug),
+	)
+	if resp.Error != nil || len(resp.Messages) == 0 {
+		return types.GuardrailResult{Tripwire: true, Reasoning: fmt.Sprintf("classifier %s failed: %v", agent.Name, resp.Error)}
+	}
+
+	answer := trimCodeFence(history.Text(resp.Messages[len(resp.Messages)-1]))
+
+	var result types.GuardrailResult
+	if err := json.Unmarshal([]byte(answer), &result); err != nil {
+		return types.GuardrailResult{Tripwire: true, Reasoning: fmt.Sprintf("classifier %s gave no verdict: %s", agent.Name, answer)}
+	}
+	return result
+}
+
+// inputText returns the text of the user messages at the end of the history, the input of a run.
+func inputText(msgs []openai.ChatCompletionMessageParamUnion) string {
+	var texts []string
+	for i := len(msgs) - 1; i >= 0 && history.Role(msgs[i]) == "user"; i-- {
+		texts = append([]string{history.Text(msgs[i])}, texts...)
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,175 @@
+package goswarm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go"
+	oaioption "github.com/openai/openai-go/option"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/session"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestGuardrails(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+
+		if messageText(req.Messages[0]) == "Classify." {
+			return assistantReply(`{"tripwire": true, "reasoning": "homework request"}`)
+		}
+		return assistantReply("The password is hunter2.")
+	})
+
+	noSecrets := types.Guardrail{
+		Name: "no secrets",
+		Check: func(ctx goswarm.Context, text string) types.GuardrailResult {
+			return types.GuardrailResult{Tripwire: strings.Contains(text, "password"), Reasoning: "leaks a password"}
+		},
+	}
+	ctx := goswarm.NewContext(context.Background())
+
+	t.Run("input", func(t *testing.T) {
+		requests = 0
+		agent := goswarm.NewAgent(option.WithAgentInputGuardrails(noSecrets))
+
+		resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("What is the admin password?")))
+
+		var tripped *types.GuardrailError
+		if !errors.As(resp.Error, &tripped) || tripped.Output || tripped.Guardrail != "no secrets" {
+			t.Fatalf("expected the input guardrail to trip, got %v", resp.Error)
+		}
+		if requests != 0 || len(resp.Messages) != 0 {
+			t.Fatalf("expected no model call, got %d requests", requests)
+		}
+	})
+
+	t.Run("concurrent classifier", func(t *testing.T) {
+		requests = 0
+		classifier := goswarm.NewAgent(option.WithAgentInstructions("Classify."))
+		agent := goswarm.NewAgent(option.WithAgentInputGuardrails(types.Guardrail{
+			Name:       "homework",
+			Agent:      classifier,
+			Concurrent: true,
+		}))
+
+		resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Solve my exercise.")))
+
+		var tripped *types.GuardrailError
+		if !errors.As(resp.Error, &tripped) || tripped.Reasoning != "homework request" {
+			t.Fatalf("expected the classifier to trip, got %v", resp.Error)
+		}
+		if requests != 2 || len(resp.Messages) != 0 {
+			t.Fatalf("expected the completion to be discarded, got %d requests and %d messages", requests, len(resp.Messages))
+		}
+	})
+
+	t.Run("output", func(t *testing.T) {
+		agent := goswarm.NewAgent(option.WithAgentOutputGuardrails(noSecrets))
+
+		resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Hi!")))
+
+		var tripped *types.GuardrailError
+		if !errors.As(resp.Error, &tripped) || !tripped.Output {
+			t.Fatalf("expected the output guardrail to trip, got %v", resp.Error)
+		}
+		if len(resp.Messages) != 0 {
+			t.Fatalf("expected the output to be withheld, got %d messages", len(resp.Messages))
+		}
+	})
+
+	t.Run("session", func(t *testing.T) {
+		store := session.NewMemoryStore()
+		agent := goswarm.NewAgent(option.WithAgentInputGuardrails(noSecrets))
+		client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+			return assistantReply("Hi.")
+		}, option.WithSessionStore(store), option.WithStartAgent(agent))
+
+		if _, err := client.RunSession(ctx, "s1", "Hi!"); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.RunSession(ctx, "s1", "What is the admin password?")
+		if err != nil || resp.Error == nil {
+			t.Fatalf("expected the input guardrail to trip, got %v, %v", resp, err)
+		}
+
+		// the rejected message is not stored in the session
+		sess, err := store.Load(ctx, "s1")
+		if err != nil || len(sess.Messages) != 2 || sess.Version != 1 {
+			t.Fatalf("expected the session of the first run, got %+v, %v", sess, err)
+		}
+	})
+}
+
+func TestGuardrailStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"The password ", "is hunter2."} {
+			data, _ := json.Marshal(map[string]any{
+				"id": "chatcmpl-test", "object": "chat.completion.chunk", "created": 0, "model": "gpt-4o",
+				"choices": []any{map[string]any{"index": 0, "delta": map[string]any{"role": "assistant", "content": delta}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := goswarm.NewSwarm(openai.NewClient(
+		oaioption.WithBaseURL(srv.URL+"/"),
+		oaioption.WithAPIKey("test"),
+		oaioption.WithMaxRetries(0),
+	))
+
+	stream := func(agent *types.Agent) (string, *types.Response) {
+		var streamed string
+		var resp *types.Response
+		for event := range client.RunAndStream(goswarm.NewContext(context.Background()), agent, goswarm.NewMessages(openai.UserMessage("Hi!"))) {
+			switch v := event.(type) {
+			case openai.ChatCompletionChunk:
+				for _, choice := range v.Choices {
+					streamed += choice.Delta.Content
+				}
+			case *types.Response:
+				resp = v
+			}
+		}
+		return streamed, resp
+	}
+
+	// the chunks are held back until the output guardrails passed
+	streamed, resp := stream(goswarm.NewAgent(option.WithAgentOutputGuardrails(types.Guardrail{
+		Name: "no secrets",
+		Check: func(ctx goswarm.Context, text string) types.GuardrailResult {
+			return types.GuardrailResult{Tripwire: strings.Contains(text, "password")}
+		},
+	})))
+	var tripped *types.GuardrailError
+	if !errors.As(resp.Error, &tripped) || streamed != "" || len(resp.Messages) != 0 {
+		t.Fatalf("expected the output to be withheld, got %q and %v", streamed, resp.Error)
+	}
+
+	streamed, resp = stream(goswarm.NewAgent(option.WithAgentOutputGuardrails(types.Guardrail{
+		Name: "nothing",
+		Check: func(ctx goswarm.Context, text string) types.GuardrailResult {
+			return types.GuardrailResult{}
+		},
+	})))
+	if resp.Error != nil || streamed != "The password is hunter2." {
+		t.Fatalf("expected the output once the guardrails passed, got %q and %v", streamed, resp.Error)
+	}
+}
@@ -0,0 +1,252 @@
+package goswarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+var __CTX_HANDOFF_NAME__ = "handoff"
+
+const handoffNote = `The handoff to %s was refused because %s. Continue the conversation as %s.`
+
+const handoffSummaryPrompt = `The conversation below is handed over to the %s agent.
+Summarise it so that the agent can continue without the original messages. Keep names,
+identifiers, decisions and open questions.
+
+Conversation:
+%s`
+
+// handoffToJSON describes the transfer tool of a handoff to the model.
+func handoffToJSON(handoff *types.Handoff) openai.ChatCompletionToolParam {
+	desc := handoff.Description
+	if desc == "" {
+		desc = fmt.Sprintf("Transfer the conversation to the %s agent.", handoff.Agent.Name)
+	}
+
+	// the payload is described by its json names, the way decodePayload reads it
+	parameters := openai.FunctionParameters{"type": "object", "properties": map[string]any{}, "required": []string{}}
+	if handoff.Payload != nil {
+		parameters = objectSchema(handoff.Payload, false, nil)
+	}
+
+	return openai.ChatCompletionToolParam{
+		Type: openai.F(openai.ChatCompletionToolTypeFunction),
+		Function: openai.F(openai.FunctionDefinitionParam{
+			Name:        openai.String(handoff.ToolName()),
+			Description: openai.String(desc),
+			Parameters:  openai.F(parameters),
+		}),
+	}
+}
+
+// callHandoff validates the payload of a handoff call. An invalid payload is reported to the
+// model and the handoff does not happen.
+func callHandoff(handoff *types.Handoff, arguments string) types.Result {
+	if _, err := decodePayload(handoff, arguments); err != nil {
+		return types.Result{Value: fmt.Sprintf("Error: invalid handoff payload: %v", err)}
+	}
+	return types.Result{
+		Value: fmt.Sprintf(`{"assistant": "%s"}`, handoff.Agent.Name),
+		Agent: handoff.Agent,
+	}
+}
+
+// decodePayload decodes the arguments of a handoff call into a value of its payload type.
+func decodePayload(handoff *types.Handoff, arguments string) (any, error) {
+	if handoff.Payload == nil {
+		return nil, nil
+	}
+
+	var args types.ContextVariables
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil, err
+	}
+	for _, name := range objectSchema(handoff.Payload, false, nil)["required"].([]string) {
+		if _, ok := args[name]; !ok {
+			return nil, fmt.Errorf("missing required field %q", name)
+		}
+	}
+
+	payload := reflect.New(handoff.Payload)
+	if err := json.Unmarshal([]byte(arguments), payload.Interface()); err != nil {
+		return nil, err
+	}
+	return payload.Elem().Interface(), nil
+}
+
+// enterAgent makes the agent active and records the handoff in the run and in the "handoff"
+// context variable. When a declarative handoff selected the agent, its input filter decides
+// what the agent sees of the history so far, the history itself is left untouched.
+// A handoff that violates the policy of the run is refused or stops the run with a *types.HandoffError.
+func (s *Swarm) enterAgent(ctx Context, state *runState, calls []openai.ChatCompletionMessageToolCall, agent *types.Agent, args option.RunOptions) error {
+	if agent == state.agent {
+		return nil
+	}
+	if state.pinned {
+		debugPrint(args.Debug, "Ignoring the handoff to %s, %s is pinned.", agent.Name, state.agent.Name)
+		return nil
+	}
+	if reason := checkHandoff(args.HandoffPolicy, state, agent); reason != "" {
+		return refuseHandoff(args.HandoffPolicy, state, agent, reason, args.Debug)
+	}
+
+	handoff, call := calledHandoff(state.agent, calls, agent)
+
+	event := types.HandoffEvent{From: state.agent.Name, To: agent.Name}
+	if handoff != nil {
+		event.Tool = handoff.ToolName()
+		event.ToolCallID = call.ID
+		event.Payload, _ = decodePayload(handoff, call.Function.Arguments)
+	}
+	state.handoffs = append(state.handoffs, event)
+	ctx.SetVariable(__CTX_HANDOFF_NAME__, event)
+	debugPrint(args.Debug, "Handoff from %s to %s.", event.From, event.To)
+
+	state.agent = agent
+	state.filtered, state.filterEnd = nil, 0
+	if handoff == nil || (handoff.SummaryAgent == nil && handoff.InputFilter == nil) {
+		return nil
+	}
+
+	view := state.history
+	if handoff.SummaryAgent != nil {
+		view = s.summarizeHandoff(ctx, handoff, view, args.Debug)
+	}
+	if handoff.InputFilter != nil {
+		view = handoff.InputFilter.Apply(view)
+	}
+
+	state.filtered = view
+	state.filterEnd = len(state.history)
+	return nil
+}
+
+// checkHandoff tells why the handoff to the agent violates the policy, or returns "".
+func checkHandoff(policy *types.HandoffPolicy, state *runState, agent *types.Agent) string {
+	if policy == nil {
+		return ""
+	}
+
+	if policy.MaxHandoffs > 0 && len(state.handoffs) >= policy.MaxHandoffs {
+		return fmt.Sprintf("the run reached the limit of %d handoffs", policy.MaxHandoffs)
+	}
+
+	if allowed, ok := policy.Allowed[state.agent.Name]; ok && !slices.Contains(allowed, agent.Name) {
+		return fmt.Sprintf("%s may not hand off to %s", state.agent.Name, agent.Name)
+	}
+
+	recent := state.handoffs[max(len(state.handoffs)-policy.PingPongWindow, 0):]
+	for _, event := range recent {
+		if event.From == agent.Name && event.To == state.agent.Name {
+			return fmt.Sprintf("%s handed off to %s moments ago", agent.Name, state.agent.Name)
+		}
+	}
+
+	return ""
+}
+
+// refuseHandoff applies the action of the policy to a handoff that violates it.
+func refuseHandoff(policy *types.HandoffPolicy, state *runState, agent *types.Agent, reason string, debug bool) error {
+	debugPrint(debug, "Handoff from %s to %s refused: %s.", state.agent.Name, agent.Name, reason)
+
+	switch policy.Action {
+	case types.PinAgent:
+		state.pinned = true
+	case types.CorrectHandoff:
+		note := policy.Note
+		if note == "" {
+			note = fmt.Sprintf(handoffNote, agent.Name, reason, state.agent.Name)
+		}
+		state.history = append(state.history, openai.SystemMessage(note))
+	default:
+		return &types.HandoffError{From: state.agent.Name, To: agent.Name, Reason: reason}
+	}
+	return nil
+}
+
+// calledHandoff returns the last handoff of the agent among the calls that leads to the target.
+func calledHandoff(agent *types.Agent, calls []openai.ChatCompletionMessageToolCall, target *types.Agent) (*types.Handoff, openai.ChatCompletionMessageToolCall) {
+	handoffs := map[string]*types.Handoff{}
+	for _, f := range agent.Functions {
+		if handoff, ok := f.(*types.Handoff); ok {
+			handoffs[handoff.ToolName()] = handoff
+		}
+	}
+
+	for i := len(calls) - 1; i >= 0; i-- {
+		if handoff, ok := handoffs[calls[i].Function.Name]; ok && handoff.Agent == target {
+			return handoff, calls[i]
+		}
+	}
+	return nil, openai.ChatCompletionMessageToolCall{}
+}
+
+// LastHandoff returns the latest handoff of the conversation, e.g. for the dynamic instructions
+// of the receiving agent. The event is decoded again when the variables went through a JSON
+// round trip, its payload is then left as a map, see HandoffPayload.
+func LastHandoff(ctx Context) (types.HandoffEvent, bool) {
+	switch v := ctx.GetVariable(__CTX_HANDOFF_NAME__, nil).(type) {
+	case types.HandoffEvent:
+		return v, true
+	case *types.HandoffEvent:
+		return *v, true
+	case map[string]any:
+		var event types.HandoffEvent
+		if data, err := json.Marshal(v); err == nil && json.Unmarshal(data, &event) == nil {
+			return event, true
+		}
+	}
+	return types.HandoffEvent{}, false
+}
+
+// HandoffPayload returns the payload of the latest handoff as a T.
+func HandoffPayload[T any](ctx Context) (T, bool) {
+	var payload T
+
+	event, ok := LastHandoff(ctx)
+	if !ok || event.Payload == nil {
+		return payload, false
+	}
+	if v, ok := event.Payload.(T); ok {
+		return v, true
+	}
+
+	data, err := json.Marshal(event.Payload)
+	if err != nil || json.Unmarshal(data, &payload) != nil {
+		return payload, false
+	}
+	return payload, true
+}
+
+// summarizeHandoff replaces every turn but the last with a summary written by the summary agent
+// of the handoff. The history is returned unchanged when the summary fails.
+func (s *Swarm) summarizeHandoff(ctx Context, handoff *types.Handoff, msgs []openai.ChatCompletionMessageParamUnion, debug bool) []openai.ChatCompletionMessageParamUnion {
+	kept := history.KeepLastTurns(1).Apply(msgs)
+	earlier := msgs[:len(msgs)-len(kept)]
+	if len(earlier) == 0 {
+		return msgs
+	}
+
+	prompt := fmt.Sprintf(handoffSummaryPrompt, handoff.Agent.Name, history.Transcript(earlier))
+	resp := s.Run(ctx, handoff.SummaryAgent, NewMessages(openai.UserMessage(prompt)),
+		option.WithModel(handoff.SummaryAgent.Model),
+		option.WithMaxTurns(1),
+		option.WithExecuteTools(false),
+		option.WithDebug(debug),
+	)
+	if runError(resp) != nil {
+		debugPrint(debug, "Handoff summary failed, sending the history unchanged.")
+		return msgs
+	}
+
+	view := []openai.ChatCompletionMessageParamUnion{history.NewSummary(finalText(resp))}
+	return append(view, kept...)
+}
@@ -0,0 +1,287 @@
+package goswarm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestHandoffs(t *testing.T) {
+	var tools []map[string]any
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if messageText(req.Messages[0]) == "Triage." {
+			tools = req.Tools
+			return toolCallReply("call_1", "transfer_to_sales_agent", `{}`)
+		}
+		return assistantReply("Buy bees!")
+	})
+
+	sales := goswarm.NewAgent(
+		option.WithAgentName("Sales Agent"),
+		option.WithAgentInstructions("Sell."),
+	)
+	refunds := goswarm.NewAgent(
+		option.WithAgentName("Refunds Agent"),
+		option.WithAgentInstructions("Refund."),
+	)
+	triage := goswarm.NewAgent(
+		option.WithAgentName("Triage Agent"),
+		option.WithAgentInstructions("Triage."),
+		option.WithAgentHandoffs(sales, goswarm.NewHandoff(refunds, option.WithHandoffDescription("For refunds."))),
+	)
+
+	resp := client.Run(goswarm.NewContext(context.Background()), triage, goswarm.NewMessages(openai.UserMessage("I want bees.")))
+
+	if resp.Agent != sales {
+		t.Fatalf("expected a handoff to sales, got %s", resp.Agent.Name)
+	}
+	if len(tools) != 2 {
+		t.Fatalf("expected two transfer tools, got %+v", tools)
+	}
+
+	refundsTool := tools[1]["function"].(map[string]any)
+	if refundsTool["name"] != "transfer_to_refunds_agent" || refundsTool["description"] != "For refunds." {
+		t.Fatalf("unexpected transfer tool: %+v", refundsTool)
+	}
+	if history.Text(resp.Messages[1]) != `{"assistant": "Sales Agent"}` {
+		t.Fatalf("unexpected tool result: %+v", resp.Messages[1])
+	}
+}
+
+func TestHandoffInputFilter(t *testing.T) {
+	var salesRequest fakeRequest
+	triageTurns := 0
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		switch messageText(req.Messages[0]) {
+		case "Summarise.":
+			return assistantReply("The user asked about the weather.")
+		case "Triage.":
+			if triageTurns++; triageTurns == 1 {
+				return assistantReply("It is sunny.")
+			}
+			return toolCallReply("call_1", "transfer_to_sales_agent", `{}`)
+		}
+		salesRequest = req
+		return assistantReply("Buy bees!")
+	})
+
+	summarizer := goswarm.NewAgent(option.WithAgentInstructions("Summarise."))
+	sales := goswarm.NewAgent(
+		option.WithAgentName("Sales Agent"),
+		option.WithAgentInstructions("Sell."),
+	)
+	triage := goswarm.NewAgent(
+		option.WithAgentName("Triage Agent"),
+		option.WithAgentInstructions("Triage."),
+		option.WithAgentHandoffs(goswarm.NewHandoff(sales,
+			option.WithHandoffSummary(summarizer),
+			option.WithHandoffInputFilter(history.StripToolCalls()),
+		)),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+	messages := goswarm.NewMessages(openai.UserMessage("How is the weather?"))
+	resp := client.Run(ctx, triage, messages)
+
+	messages = append(messages, resp.Messages...)
+	messages = append(messages, openai.UserMessage("I want bees."))
+	resp = client.Run(ctx, triage, messages)
+
+	if resp.Agent != sales {
+		t.Fatalf("expected a handoff to sales, got %s", resp.Agent.Name)
+	}
+	// tool call, tool result and the answer of sales
+	if len(resp.Messages) != 3 {
+		t.Fatalf("expected the full history in the response, got %d messages", len(resp.Messages))
+	}
+
+	// system prompt, summary, last user message
+	seen := salesRequest.Messages
+	if len(seen) != 3 {
+		t.Fatalf("expected the filtered history, got %+v", seen)
+	}
+	if !strings.Contains(messageText(seen[1]), "The user asked about the weather.") || messageText(seen[2]) != "I want bees." {
+		t.Fatalf("unexpected filtered history: %+v", seen)
+	}
+}
+
+type escalation struct {
+	Reason  string `desc:"Why the conversation is handed over." required:"true"`
+	OrderID string `desc:"The order the user talks about."`
+}
+
+func TestHandoffPayload(t *testing.T) {
+	var triageRequests []fakeRequest
+	var salesPrompt string
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if messageText(req.Messages[0]) == "Triage." {
+			triageRequests = append(triageRequests, req)
+			if len(triageRequests) == 1 {
+				return toolCallReply("call_1", "transfer_to_sales_agent", `{"OrderID": "o-1"}`)
+			}
+			return toolCallReply("call_2", "transfer_to_sales_agent", `{"Reason": "wants bees", "OrderID": "o-1"}`)
+		}
+		salesPrompt = messageText(req.Messages[0])
+		return assistantReply("Buy bees!")
+	})
+
+	sales := goswarm.NewAgent(
+		option.WithAgentName("Sales Agent"),
+		option.WithAgentInstructions(func(ctx goswarm.Context) string {
+			payload, _ := goswarm.HandoffPayload[escalation](ctx)
+			return "Sell. reason=" + payload.Reason + " order=" + payload.OrderID
+		}),
+	)
+	triage := goswarm.NewAgent(
+		option.WithAgentName("Triage Agent"),
+		option.WithAgentInstructions("Triage."),
+		option.WithAgentHandoffs(goswarm.NewHandoff(sales, option.WithHandoffPayload(escalation{}))),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+	resp := client.Run(ctx, triage, goswarm.NewMessages(openai.UserMessage("I want bees.")))
+
+	params := triageRequests[0].Tools[0]["function"].(map[string]any)["parameters"].(map[string]any)
+	if required := params["required"].([]any); len(required) != 1 || required[0] != "Reason" {
+		t.Fatalf("unexpected payload schema: %+v", params)
+	}
+	if len(triageRequests) != 2 || !strings.Contains(history.Text(resp.Messages[1]), "missing required field") {
+		t.Fatalf("expected the invalid payload to be reported, got %+v", resp.Messages)
+	}
+
+	if resp.Agent != sales || salesPrompt != "Sell. reason=wants bees order=o-1" {
+		t.Fatalf("unexpected handoff: %s, %q", resp.Agent.Name, salesPrompt)
+	}
+	if len(resp.Handoffs) != 1 {
+		t.Fatalf("expected one handoff event, got %+v", resp.Handoffs)
+	}
+	event := resp.Handoffs[0]
+	if event.From != "Triage Agent" || event.To != "Sales Agent" || event.ToolCallID != "call_2" {
+		t.Fatalf("unexpected handoff event: %+v", event)
+	}
+	if last, _ := goswarm.LastHandoff(ctx); last.ToolCallID != "call_2" {
+		t.Fatalf("expected the event in the context variables, got %+v", last)
+	}
+}
+
+type refundRequest struct {
+	OrderID string `json:"order_id" desc:"The order to refund." required:"true"`
+	Note    string `json:"note,omitempty"`
+}
+
+func TestHandoffPayloadJSONTags(t *testing.T) {
+	var params map[string]any
+	var refundPrompt string
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if messageText(req.Messages[0]) == "Triage." {
+			params = req.Tools[0]["function"].(map[string]any)["parameters"].(map[string]any)
+			return toolCallReply("call_1", "transfer_to_refunds_agent", `{"order_id": "o-1"}`)
+		}
+		refundPrompt = messageText(req.Messages[0])
+		return assistantReply("Refunded.")
+	})
+
+	refunds := goswarm.NewAgent(
+		option.WithAgentName("Refunds Agent"),
+		option.WithAgentInstructions(func(ctx goswarm.Context) string {
+			payload, _ := goswarm.HandoffPayload[refundRequest](ctx)
+			return "Refund order=" + payload.OrderID
+		}),
+	)
+	triage := goswarm.NewAgent(
+		option.WithAgentInstructions("Triage."),
+		option.WithAgentHandoffs(goswarm.NewHandoff(refunds, option.WithHandoffPayload(refundRequest{}))),
+	)
+
+	resp := client.Run(goswarm.NewContext(context.Background()), triage, goswarm.NewMessages(openai.UserMessage("Refund o-1.")))
+
+	// the model sees the json names the payload is decoded with
+	properties := params["properties"].(map[string]any)
+	if _, ok := properties["order_id"]; !ok || len(properties) != 2 {
+		t.Fatalf("expected the json names in the schema, got %+v", params)
+	}
+	if required := params["required"].([]any); len(required) != 1 || required[0] != "order_id" {
+		t.Fatalf("expected order_id to be required, got %+v", params)
+	}
+	if resp.Agent != refunds || refundPrompt != "Refund order=o-1" {
+		t.Fatalf("unexpected handoff: %s, %q", resp.Agent.Name, refundPrompt)
+	}
+}
+
+func TestHandoffPolicy(t *testing.T) {
+	// triage and sales hand the conversation back and forth until one of them answers
+	newClient := func() *goswarm.Swarm {
+		calls := 0
+		return newFakeSwarm(t, func(req fakeRequest) map[string]any {
+			calls++
+			if calls > 4 {
+				return assistantReply("Done.")
+			}
+			if messageText(req.Messages[0]) == "Triage." {
+				return toolCallReply(fmt.Sprintf("call_%d", calls), "transfer_to_sales_agent", `{}`)
+			}
+			return toolCallReply(fmt.Sprintf("call_%d", calls), "transfer_to_triage_agent", `{}`)
+		})
+	}
+
+	sales := goswarm.NewAgent(
+		option.WithAgentName("Sales Agent"),
+		option.WithAgentInstructions("Sell."),
+	)
+	triage := goswarm.NewAgent(
+		option.WithAgentName("Triage Agent"),
+		option.WithAgentInstructions("Triage."),
+		option.WithAgentHandoffs(sales),
+	)
+	sales.Functions = append(sales.Functions, goswarm.NewHandoff(triage))
+
+	messages := goswarm.NewMessages(openai.UserMessage("I want bees."))
+
+	resp := newClient().Run(goswarm.NewContext(context.Background()), triage, messages,
+		option.WithHandoffPolicy(types.HandoffPolicy{PingPongWindow: 2}),
+	)
+	var handoffErr *types.HandoffError
+	if !errors.As(resp.Error, &handoffErr) || handoffErr.From != "Sales Agent" || handoffErr.To != "Triage Agent" {
+		t.Fatalf("expected the ping-pong to stop the run, got %v", resp.Error)
+	}
+
+	resp = newClient().Run(goswarm.NewContext(context.Background()), triage, messages,
+		option.WithHandoffPolicy(types.HandoffPolicy{PingPongWindow: 2, Action: types.CorrectHandoff}),
+	)
+	if resp.Error != nil || resp.Agent != sales || len(resp.Handoffs) != 1 {
+		t.Fatalf("expected sales to keep the conversation, got %s after %+v", resp.Agent.Name, resp.Handoffs)
+	}
+	if !strings.Contains(history.Text(resp.Messages[4]), "The handoff to Triage Agent was refused") {
+		t.Fatalf("expected a corrective note, got %+v", resp.Messages[4])
+	}
+
+	resp = newClient().Run(goswarm.NewContext(context.Background()), triage, messages,
+		option.WithHandoffPolicy(types.HandoffPolicy{
+			Allowed: map[string][]string{"Triage Agent": {"Refunds Agent"}},
+			Action:  types.PinAgent,
+		}),
+	)
+	if resp.Error != nil || resp.Agent != triage || len(resp.Handoffs) != 0 {
+		t.Fatalf("expected triage to be pinned, got %s after %+v", resp.Agent.Name, resp.Handoffs)
+	}
+
+	resp = newClient().Run(goswarm.NewContext(context.Background()), triage, messages,
+		option.WithHandoffPolicy(types.HandoffPolicy{MaxHandoffs: 3}),
+	)
+	if !errors.As(resp.Error, &handoffErr) || len(resp.Handoffs) != 3 {
+		t.Fatalf("expected the fourth handoff to stop the run, got %v after %+v", resp.Error, resp.Handoffs)
+	}
+}
@@ -0,0 +1,510 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// FormatVersion is the version of the JSON encoding written by Marshal.
+const FormatVersion = 1
+
+// Message kinds, one per concrete type implementing openai.ChatCompletionMessageParamUnion.
+const (
+	kindCompletion = "completion" // openai.ChatCompletionMessage, as returned by the model
+	kindSystem     = "system"
+	kindUser       = "user"
+	kindAssistant  = "assistant"
+	kindTool       = "tool"
+	kindFunction   = "function"
+	kindGeneric    = "generic" // openai.ChatCompletionMessageParam
+)
+
+type document struct {
+	Version  int       `json:"version"`
+	Messages []message `json:"messages"`
+}
+
+// message is the canonical JSON form of a history message. Kind records the Go type so that
+// decoding gives back the same type that was encoded.
+type message struct {
+	Kind         string        `json:"kind"`
+	Role         string        `json:"role,omitempty"`
+	Name         string        `json:"name,omitempty"`
+	Content      *string       `json:"content,omitempty"`
+	Parts        []part        `json:"parts,omitempty"`
+	Refusal      string        `json:"refusal,omitempty"`
+	ToolCalls    []toolCall    `json:"tool_calls,omitempty"`
+	ToolCallID   string        `json:"tool_call_id,omitempty"`
+	FunctionCall *functionCall `json:"function_call,omitempty"`
+	Audio        *audio        `json:"audio,omitempty"`
+}
+
+type part struct {
+	Type        string `json:"type"` // text, refusal, image_url or input_audio
+	Text        string `json:"text,omitempty"`
+	Refusal     string `json:"refusal,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+	AudioData   string `json:"audio_data,omitempty"`
+	AudioFormat string `json:"audio_format,omitempty"`
+}
+
+type toolCall struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type functionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type audio struct {
+	ID         string `json:"id"`
+	Data       string `json:"data,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// Marshal encodes the history into the canonical, versioned JSON format.
+func Marshal(history []openai.ChatCompletionMessageParamUnion) ([]byte, error) {
+	doc := document{Version: FormatVersion, Messages: []message{}}
+
+	for i, msg := range history {
+		m, err := encodeMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("history: message %d: %w", i, err)
+		}
+		doc.Messages = append(doc.Messages, m)
+	}
+
+	return json.Marshal(doc)
+}
+
+// Unmarshal decodes a history encoded by Marshal.
+func Unmarshal(data []byte) ([]openai.ChatCompletionMessageParamUnion, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > FormatVersion {
+		return nil, fmt.Errorf("history: unsupported format version %d", doc.Version)
+	}
+
+	history := []openai.ChatCompletionMessageParamUnion{}
+	for i, m := range doc.Messages {
+		msg, err := decodeMessage(m)
+		if err != nil {
+			return nil, fmt.Errorf("history: message %d: %w", i, err)
+		}
+		history = append(history, msg)
+	}
+
+	return history, nil
+}
+
+func encodeMessage(msg openai.ChatCompletionMessageParamUnion) (message, error) {
+	switch v := msg.(type) {
+	case openai.ChatCompletionMessage:
+		m := message{
+			Kind:      kindCompletion,
+			Role:      string(v.Role),
+			Content:   &v.Content,
+			Refusal:   v.Refusal,
+			ToolCalls: encodeToolCalls(v.ToolCalls),
+		}
+		if v.FunctionCall.Name != "" {
+			m.FunctionCall = &functionCall{Name: v.FunctionCall.Name, Arguments: v.FunctionCall.Arguments}
+		}
+		if v.Audio.ID != "" {
+			m.Audio = &audio{ID: v.Audio.ID, Data: v.Audio.Data, ExpiresAt: v.Audio.ExpiresAt, Transcript: v.Audio.Transcript}
+		}
+		return m, nil
+
+	case openai.ChatCompletionSystemMessageParam:
+		m := message{Kind: kindSystem, Role: "system", Name: v.Name.Value}
+		for _, p := range v.Content.Value {
+			m.Parts = append(m.Parts, part{Type: "text", Text: p.Text.Value})
+		}
+		return m, nil
+
+	case openai.ChatCompletionUserMessageParam:
+		m := message{Kind: kindUser, Role: "user", Name: v.Name.Value}
+		for _, p := range v.Content.Value {
+			encoded, err := encodePart(p)
+			if err != nil {
+				return message{}, err
+			}
+			m.Parts = append(m.Parts, encoded)
+		}
+		return m, nil
+
+	case openai.ChatCompletionAssistantMessageParam:
+		m := message{
+			Kind:      kindAssistant,
+			Role:      "assistant",
+			Name:      v.Name.Value,
+			Refusal:   v.Refusal.Value,
+			ToolCalls: encodeToolCalls(toolCallsFromParams(v.ToolCalls.Value)),
+		}
+		for _, p := range v.Content.Value {
+			switch p := p.(type) {
+			case openai.ChatCompletionContentPartTextParam:
+				m.Parts = append(m.Parts, part{Type: "text", Text: p.Text.Value})
+			case openai.ChatCompletionContentPartRefusalParam:
+				m.Parts = append(m.Parts, part{Type: "refusal", Refusal: p.Refusal.Value})
+			case openai.ChatCompletionAssistantMessageParamContent:
+				m.Parts = append(m.Parts, part{Type: string(p.Type.Value), Text: p.Text.Value, Refusal: p.Refusal.Value})
+			}
+		}
+		if v.FunctionCall.Present {
+			fc := v.FunctionCall.Value
+			m.FunctionCall = &functionCall{Name: fc.Name.Value, Arguments: fc.Arguments.Value}
+		}
+		if v.Audio.Present {
+			m.Audio = &audio{ID: v.Audio.Value.ID.Value}
+		}
+		return m, nil
+
+	case openai.ChatCompletionToolMessageParam:
+		m := message{Kind: kindTool, Role: "tool", ToolCallID: v.ToolCallID.Value}
+		for _, p := range v.Content.Value {
+			m.Parts = append(m.Parts, part{Type: "text", Text: p.Text.Value})
+		}
+		return m, nil
+
+	case openai.ChatCompletionFunctionMessageParam:
+		content := v.Content.Value
+		return message{Kind: kindFunction, Role: "function", Name: v.Name.Value, Content: &content}, nil
+
+	case openai.ChatCompletionMessageParam:
+		m := message{
+			Kind:       kindGeneric,
+			Role:       string(v.Role.Value),
+			Name:       v.Name.Value,
+			Refusal:    v.Refusal.Value,
+			ToolCalls:  encodeToolCalls(ToolCalls(v)),
+			ToolCallID: v.ToolCallID.Value,
+		}
+		switch content := v.Content.Value.(type) {
+		case string:
+			m.Content = &content
+		case nil:
+		default:
+			// a slice of any of the part types, encoded through its JSON form
+			var raw []rawPart
+			if err := convertJSON(content, &raw); err != nil {
+				return message{}, fmt.Errorf("unsupported content %T: %w", content, err)
+			}
+			for _, r := range raw {
+				m.Parts = append(m.Parts, r.part())
+			}
+		}
+		if v.FunctionCall.Value != nil {
+			m.FunctionCall = &functionCall{}
+			if err := convertJSON(v.FunctionCall.Value, m.FunctionCall); err != nil {
+				return message{}, fmt.Errorf("unsupported function call %T: %w", v.FunctionCall.Value, err)
+			}
+		}
+		if v.Audio.Value != nil {
+			m.Audio = &audio{}
+			if err := convertJSON(v.Audio.Value, m.Audio); err != nil {
+				return message{}, fmt.Errorf("unsupported audio %T: %w", v.Audio.Value, err)
+			}
+		}
+		return m, nil
+	}
+
+	return message{}, fmt.Errorf("unsupported message type %T", msg)
+}
+
+func encodePart(p openai.ChatCompletionContentPartUnionParam) (part, error) {
+	switch p := p.(type) {
+	case openai.ChatCompletionContentPartTextParam:
+		return part{Type: "text", Text: p.Text.Value}, nil
+	case openai.ChatCompletionContentPartImageParam:
+		return part{Type: "image_url", ImageURL: p.ImageURL.Value.URL.Value, Detail: string(p.ImageURL.Value.Detail.Value)}, nil
+	case openai.ChatCompletionContentPartInputAudioParam:
+		return part{Type: "input_audio", AudioData: p.InputAudio.Value.Data.Value, AudioFormat: string(p.InputAudio.Value.Format.Value)}, nil
+	case openai.ChatCompletionContentPartParam:
+		// the generic part carries its payload as raw JSON values
+		var raw rawPart
+		if err := convertJSON(p, &raw); err != nil {
+			return part{}, err
+		}
+		return raw.part(), nil
+	}
+	return part{}, fmt.Errorf("unsupported content part type %T", p)
+}
+
+// rawPart is a content part in the JSON form the API receives, whatever Go type it was built from.
+type rawPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Refusal  string `json:"refusal"`
+	ImageURL struct {
+		URL    string `json:"url"`
+		Detail string `json:"detail"`
+	} `json:"image_url"`
+	InputAudio struct {
+		Data   string `json:"data"`
+		Format string `json:"format"`
+	} `json:"input_audio"`
+}
+
+func (r rawPart) part() part {
+	return part{
+		Type:        r.Type,
+		Text:        r.Text,
+		Refusal:     r.Refusal,
+		ImageURL:    r.ImageURL.URL,
+		Detail:      r.ImageURL.Detail,
+		AudioData:   r.InputAudio.Data,
+		AudioFormat: r.InputAudio.Format,
+	}
+}
+
+// convertJSON converts v into out through its JSON form.
+func convertJSON(v any, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func encodeToolCalls(calls []openai.ChatCompletionMessageToolCall) []toolCall {
+	var encoded []toolCall
+	for _, c := range calls {
+		encoded = append(encoded, toolCall{ID: c.ID, Type: string(c.Type), Name: c.Function.Name, Arguments: c.Function.Arguments})
+	}
+	return encoded
+}
+
+func decodeToolCalls(calls []toolCall) []openai.ChatCompletionMessageToolCall {
+	var decoded []openai.ChatCompletionMessageToolCall
+	for _, c := range calls {
+		decoded = append(decoded, openai.ChatCompletionMessageToolCall{
+			ID:   c.ID,
+			Type: openai.ChatCompletionMessageToolCallType(c.Type),
+			Function: openai.ChatCompletionMessageToolCallFunction{
+				Name:      c.Name,
+				Arguments: c.Arguments,
+			},
+		})
+	}
+	return decoded
+}
+
+func decodeToolCallParams(calls []toolCall) []openai.ChatCompletionMessageToolCallParam {
+	var decoded []openai.ChatCompletionMessageToolCallParam
+	for _, c := range calls {
+		decoded = append(decoded, openai.ChatCompletionMessageToolCallParam{
+			ID:   openai.F(c.ID),
+			Type: openai.F(openai.ChatCompletionMessageToolCallType(c.Type)),
+			Function: openai.F(openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      openai.F(c.Name),
+				Arguments: openai.F(c.Arguments),
+			}),
+		})
+	}
+	return decoded
+}
+
+func decodePart(p part) (openai.ChatCompletionContentPartUnionParam, error) {
+	switch p.Type {
+	case "text":
+		return openai.TextPart(p.Text), nil
+	case "image_url":
+		image := openai.ImagePart(p.ImageURL)
+		if p.Detail != "" {
+			url := image.ImageURL.Value
+			url.Detail = openai.F(openai.ChatCompletionContentPartImageImageURLDetail(p.Detail))
+			image.ImageURL = openai.F(url)
+		}
+		return image, nil
+	case "input_audio":
+		return openai.ChatCompletionContentPartInputAudioParam{
+			Type: openai.F(openai.ChatCompletionContentPartInputAudioTypeInputAudio),
+			InputAudio: openai.F(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+				Data:   openai.F(p.AudioData),
+				Format: openai.F(openai.ChatCompletionContentPartInputAudioInputAudioFormat(p.AudioFormat)),
+			}),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported content part type %q", p.Type)
+}
+
+func textParts(parts []part) []openai.ChatCompletionContentPartTextParam {
+	texts := []openai.ChatCompletionContentPartTextParam{}
+	for _, p := range parts {
+		texts = append(texts, openai.TextPart(p.Text))
+	}
+	return texts
+}
+
+// genericParts decodes the content parts of a generic message: assistant parts when there is a
+// refusal among them, user parts otherwise.
+func genericParts(parts []part) (interface{}, error) {
+	for _, p := range parts {
+		if p.Type == "refusal" {
+			content := []openai.ChatCompletionAssistantMessageParamContentUnion{}
+			for _, p := range parts {
+				if p.Type == "refusal" {
+					content = append(content, openai.RefusalPart(p.Refusal))
+				} else {
+					content = append(content, openai.TextPart(p.Text))
+				}
+			}
+			return content, nil
+		}
+	}
+
+	content := []openai.ChatCompletionContentPartUnionParam{}
+	for _, p := range parts {
+		decoded, err := decodePart(p)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, decoded)
+	}
+	return content, nil
+}
+
+func decodeMessage(m message) (openai.ChatCompletionMessageParamUnion, error) {
+	switch m.Kind {
+	case kindCompletion:
+		msg := openai.ChatCompletionMessage{
+			Role:      openai.ChatCompletionMessageRole(m.Role),
+			Refusal:   m.Refusal,
+			ToolCalls: decodeToolCalls(m.ToolCalls),
+		}
+		if m.Content != nil {
+			msg.Content = *m.Content
+		}
+		if m.FunctionCall != nil {
+			msg.FunctionCall = openai.ChatCompletionMessageFunctionCall{Name: m.FunctionCall.Name, Arguments: m.FunctionCall.Arguments}
+		}
+		if m.Audio != nil {
+			msg.Audio = openai.ChatCompletionAudio{ID: m.Audio.ID, Data: m.Audio.Data, ExpiresAt: m.Audio.ExpiresAt, Transcript: m.Audio.Transcript}
+		}
+		return msg, nil
+
+	case kindSystem:
+		msg := openai.ChatCompletionSystemMessageParam{
+			Role:    openai.F(openai.ChatCompletionSystemMessageParamRoleSystem),
+			Content: openai.F(textParts(m.Parts)),
+		}
+		if m.Name != "" {
+			msg.Name = openai.F(m.Name)
+		}
+		return msg, nil
+
+	case kindUser:
+		parts := []openai.ChatCompletionContentPartUnionParam{}
+		for _, p := range m.Parts {
+			decoded, err := decodePart(p)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, decoded)
+		}
+		msg := openai.UserMessageParts(parts...)
+		if m.Name != "" {
+			msg.Name = openai.F(m.Name)
+		}
+		return msg, nil
+
+	case kindAssistant:
+		msg := openai.ChatCompletionAssistantMessageParam{
+			Role: openai.F(openai.ChatCompletionAssistantMessageParamRoleAssistant),
+		}
+		if len(m.Parts) > 0 {
+			content := []openai.ChatCompletionAssistantMessageParamContentUnion{}
+			for _, p := range m.Parts {
+				if p.Type == "refusal" {
+					content = append(content, openai.RefusalPart(p.Refusal))
+				} else {
+					content = append(content, openai.TextPart(p.Text))
+				}
+			}
+			msg.Content = openai.F(content)
+		}
+		if m.Name != "" {
+			msg.Name = openai.F(m.Name)
+		}
+		if m.Refusal != "" {
+			msg.Refusal = openai.F(m.Refusal)
+		}
+		if len(m.ToolCalls) > 0 {
+			msg.ToolCalls = openai.F(decodeToolCallParams(m.ToolCalls))
+		}
+		if m.FunctionCall != nil {
+			msg.FunctionCall = openai.F(openai.ChatCompletionAssistantMessageParamFunctionCall{
+				Name:      openai.F(m.FunctionCall.Name),
+				Arguments: openai.F(m.FunctionCall.Arguments),
+			})
+		}
+		if m.Audio != nil {
+			msg.Audio = openai.F(openai.ChatCompletionAssistantMessageParamAudio{ID: openai.F(m.Audio.ID)})
+		}
+		return msg, nil
+
+	case kindTool:
+		return openai.ChatCompletionToolMessageParam{
+			Role:       openai.F(openai.ChatCompletionToolMessageParamRoleTool),
+			ToolCallID: openai.F(m.ToolCallID),
+			Content:    openai.F(textParts(m.Parts)),
+		}, nil
+
+	case kindFunction:
+		content := ""
+		if m.Content != nil {
+			content = *m.Content
+		}
+		return openai.FunctionMessage(m.Name, content), nil
+
+	case kindGeneric:
+		msg := openai.ChatCompletionMessageParam{
+			Role: openai.F(openai.ChatCompletionMessageParamRole(m.Role)),
+		}
+		if m.Content != nil {
+			msg.Content = openai.F[interface{}](*m.Content)
+		}
+		if len(m.Parts) > 0 {
+			content, err := genericParts(m.Parts)
+			if err != nil {
+				return nil, err
+			}
+			msg.Content = openai.F(content)
+		}
+		if m.Name != "" {
+			msg.Name = openai.F(m.Name)
+		}
+		if m.FunctionCall != nil {
+			msg.FunctionCall = openai.F[interface{}](openai.ChatCompletionAssistantMessageParamFunctionCall{
+				Name:      openai.F(m.FunctionCall.Name),
+				Arguments: openai.F(m.FunctionCall.Arguments),
+			})
+		}
+		if m.Audio != nil {
+			msg.Audio = openai.F[interface{}](openai.ChatCompletionAssistantMessageParamAudio{ID: openai.F(m.Audio.ID)})
+		}
+		if m.Refusal != "" {
+			msg.Refusal = openai.F(m.Refusal)
+		}
+		if len(m.ToolCalls) > 0 {
+			msg.ToolCalls = openai.F[interface{}](decodeToolCalls(m.ToolCalls))
+		}
+		if m.ToolCallID != "" {
+			msg.ToolCallID = openai.F(m.ToolCallID)
+		}
+		return msg, nil
+	}
+
+	return nil, fmt.Errorf("unknown message kind %q", m.Kind)
+}
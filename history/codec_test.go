@@ -0,0 +1,130 @@
+package history_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	refusal := openai.AssistantMessage("")
+	refusal.Content = openai.F([]openai.ChatCompletionAssistantMessageParamContentUnion{openai.RefusalPart("I can't help with that.")})
+	refusal.Refusal = openai.F("I can't help with that.")
+
+	msgs := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("You are a helpful agent."),
+		history.NewSummary("The user ordered bees."),
+		openai.UserMessageParts(
+			openai.TextPart("What is in this picture?"),
+			openai.ImagePart("data:image/png;base64,iVBORw0KGgo="),
+		),
+		toolCallMessage("call_1"),
+		openai.ToolMessage("call_1", `{"status": "shipped"}`),
+		openai.ChatCompletionMessage{Role: openai.ChatCompletionMessageRoleAssistant, Refusal: "no", Content: "Your order shipped."},
+		refusal,
+		openai.FunctionMessage("lookup", "legacy result"),
+	}
+
+	data, err := history.Marshal(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := history.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(msgs) {
+		t.Fatalf("expected %d messages, got %d", len(msgs), len(decoded))
+	}
+
+	for i := range msgs {
+		if reflect.TypeOf(decoded[i]) != reflect.TypeOf(msgs[i]) {
+			t.Errorf("message %d: expected %T, got %T", i, msgs[i], decoded[i])
+		}
+
+		// the decoded message must produce the same request body
+		want, _ := json.Marshal(msgs[i])
+		got, _ := json.Marshal(decoded[i])
+		if string(want) != string(got) {
+			t.Errorf("message %d:\nwant %s\ngot  %s", i, want, got)
+		}
+	}
+
+	if !history.IsSummary(decoded[1]) {
+		t.Error("summary marker was lost")
+	}
+	if got := decoded[5].(openai.ChatCompletionMessage).Refusal; got != "no" {
+		t.Errorf("refusal was lost: %q", got)
+	}
+}
+
+func TestUnmarshalRejectsUnknownVersion(t *testing.T) {
+	if _, err := history.Unmarshal([]byte(`{"version": 99, "messages": []}`)); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}
+
+func TestMarshalRoundTripGenericMessages(t *testing.T) {
+	image := openai.ImagePart("data:image/png;base64,iVBORw0KGgo=")
+	url := image.ImageURL.Value
+	url.Detail = openai.F(openai.ChatCompletionContentPartImageImageURLDetailLow)
+	image.ImageURL = openai.F(url)
+
+	msgs := []openai.ChatCompletionMessageParamUnion{
+		openai.ChatCompletionMessageParam{
+			Role: openai.F(openai.ChatCompletionMessageParamRoleUser),
+			Content: openai.F[interface{}]([]openai.ChatCompletionContentPartUnionParam{
+				openai.TextPart("What is in this picture?"),
+				image,
+				openai.ChatCompletionContentPartInputAudioParam{
+					Type: openai.F(openai.ChatCompletionContentPartInputAudioTypeInputAudio),
+					InputAudio: openai.F(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+						Data:   openai.F("UklGRg=="),
+						Format: openai.F(openai.ChatCompletionContentPartInputAudioInputAudioFormatWAV),
+					}),
+				},
+			}),
+		},
+		openai.ChatCompletionMessageParam{
+			Role: openai.F(openai.ChatCompletionMessageParamRoleAssistant),
+			FunctionCall: openai.F[interface{}](openai.ChatCompletionMessageFunctionCall{
+				Name:      "lookup",
+				Arguments: `{"order_id": "A-1"}`,
+			}),
+			Audio: openai.F[interface{}](openai.ChatCompletionAssistantMessageParamAudio{ID: openai.F("audio_1")}),
+		},
+		openai.ChatCompletionMessageParam{
+			Role: openai.F(openai.ChatCompletionMessageParamRoleAssistant),
+			Content: openai.F[interface{}]([]openai.ChatCompletionAssistantMessageParamContentUnion{
+				openai.TextPart("Sorry."),
+				openai.RefusalPart("I can't help with that."),
+			}),
+		},
+	}
+
+	data, err := history.Marshal(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := history.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range msgs {
+		want, _ := json.Marshal(msgs[i])
+		got, _ := json.Marshal(decoded[i])
+		if string(want) != string(got) {
+			t.Errorf("message %d:\nwant %s\ngot  %s", i, want, got)
+		}
+	}
+
+	if len(history.Media(decoded[0])) != 2 || history.Text(decoded[0]) != "What is in this picture?" {
+		t.Errorf("expected the parts of the generic message, got %+v", decoded[0])
+	}
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// Rough token costs of media parts, see the OpenAI vision and audio guides.
+const (
+	imageBaseTokens    = 85
+	imageTileTokens    = 170
+	imageDefaultTokens = imageBaseTokens + 4*imageTileTokens // a 1024x1024 image in high detail
+	audioTokensPerSec  = 10
+	mp3BytesPerSec     = 16000 // 128 kbit/s
+)
+
+// Media returns the image and audio parts of the message. There are no file parts, the version of
+// openai-go this module uses does not have them.
+func Media(msg openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionContentPartUnionParam {
+	var content []openai.ChatCompletionContentPartUnionParam
+	switch v := msg.(type) {
+	case openai.ChatCompletionUserMessageParam:
+		content = v.Content.Value
+	case openai.ChatCompletionMessageParam:
+		content, _ = v.Content.Value.([]openai.ChatCompletionContentPartUnionParam)
+	}
+
+	var parts []openai.ChatCompletionContentPartUnionParam
+	for _, part := range content {
+		switch part.(type) {
+		case openai.ChatCompletionContentPartImageParam, openai.ChatCompletionContentPartInputAudioParam:
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// MediaTokens estimates the tokens of an image or audio part. Images are measured when they are
+// embedded as data URLs, other images count as 1024x1024. Audio is measured by its duration.
+func MediaTokens(part openai.ChatCompletionContentPartUnionParam) int {
+	switch p := part.(type) {
+	case openai.ChatCompletionContentPartImageParam:
+		url := p.ImageURL.Value
+		if url.Detail.Value == openai.ChatCompletionContentPartImageImageURLDetailLow {
+			return imageBaseTokens
+		}
+		width, height, ok := imageSize(url.URL.Value)
+		if !ok {
+			return imageDefaultTokens
+		}
+		return imageTokens(width, height)
+	case openai.ChatCompletionContentPartInputAudioParam:
+		audio := p.InputAudio.Value
+		data, err := base64.StdEncoding.DecodeString(audio.Data.Value)
+		if err != nil {
+			return 0
+		}
+		seconds := float64(len(data)) / mp3BytesPerSec
+		if audio.Format.Value == openai.ChatCompletionContentPartInputAudioInputAudioFormatWAV && len(data) > 44 {
+			if byteRate := binary.LittleEndian.Uint32(data[28:32]); byteRate > 0 {
+				seconds = float64(len(data)-44) / float64(byteRate)
+			}
+		}
+		return int(math.Ceil(seconds * audioTokensPerSec))
+	}
+	return 0
+}
+
+// imageTokens scales the image to fit 2048x2048 and its shorter side to 768, then counts its 512px tiles.
+func imageTokens(width, height int) int {
+	w, h := float64(width), float64(height)
+	if scale := 2048 / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	if scale := 768 / math.Min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return imageBaseTokens + tiles*imageTileTokens
+}
+
+// imageSize decodes the size of an image embedded as a base64 data URL.
+func imageSize(url string) (int, int, bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return 0, 0, false
+	}
+	_, payload, found := strings.Cut(url, ";base64,")
+	if !found {
+		return 0, 0, false
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, 0, false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
@@ -0,0 +1,52 @@
+package history_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+)
+
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestMediaTokens(t *testing.T) {
+	msg := openai.UserMessageParts(
+		openai.TextPart("What is this?"),
+		openai.ImagePart(pngDataURL(t, 1024, 1024)),
+		openai.ImagePart("https://example.com/cat.png"),
+	)
+
+	media := history.Media(msg)
+	if len(media) != 2 {
+		t.Fatalf("expected two media parts, got %d", len(media))
+	}
+
+	// 1024x1024 is scaled to 768x768, four tiles
+	if tokens := history.MediaTokens(media[0]); tokens != 85+4*170 {
+		t.Fatalf("unexpected tokens of the embedded image: %d", tokens)
+	}
+	if tokens := history.MediaTokens(media[1]); tokens != 85+4*170 {
+		t.Fatalf("unexpected tokens of the linked image: %d", tokens)
+	}
+	if tokens := history.EstimateTokens(msg); tokens < 2*(85+4*170) {
+		t.Fatalf("expected the estimate to include the images, got %d", tokens)
+	}
+
+	small := openai.ImagePart(pngDataURL(t, 100, 100))
+	if tokens := history.MediaTokens(small); tokens != 85+170 {
+		t.Fatalf("unexpected tokens of the small image: %d", tokens)
+	}
+}
@@ -0,0 +1,335 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// Role returns the role of the message author ("system", "user", "assistant", "tool" or "function").
+func Role(msg openai.ChatCompletionMessageParamUnion) string {
+	switch v := msg.(type) {
+	case openai.ChatCompletionMessage:
+		return string(v.Role)
+	case openai.ChatCompletionSystemMessageParam:
+		return "system"
+	case openai.ChatCompletionUserMessageParam:
+		return "user"
+	case openai.ChatCompletionAssistantMessageParam:
+		return "assistant"
+	case openai.ChatCompletionToolMessageParam:
+		return "tool"
+	case openai.ChatCompletionFunctionMessageParam:
+		return "function"
+	case openai.ChatCompletionMessageParam:
+		return string(v.Role.Value)
+	}
+	return ""
+}
+
+// Text returns the text content of the message. Text parts are joined with a newline.
+func Text(msg openai.ChatCompletionMessageParamUnion) string {
+	var texts []string
+
+	switch v := msg.(type) {
+	case openai.ChatCompletionMessage:
+		return v.Content
+	case openai.ChatCompletionSystemMessageParam:
+		for _, part := range v.Content.Value {
+			texts = append(texts, part.Text.Value)
+		}
+	case openai.ChatCompletionUserMessageParam:
+		for _, part := range v.Content.Value {
+			if text, ok := part.(openai.ChatCompletionContentPartTextParam); ok {
+				texts = append(texts, text.Text.Value)
+			}
+		}
+	case openai.ChatCompletionAssistantMessageParam:
+		for _, part := range v.Content.Value {
+			if text, ok := part.(openai.ChatCompletionContentPartTextParam); ok {
+				texts = append(texts, text.Text.Value)
+			}
+		}
+	case openai.ChatCompletionToolMessageParam:
+		for _, part := range v.Content.Value {
+			texts = append(texts, part.Text.Value)
+		}
+	case openai.ChatCompletionFunctionMessageParam:
+		return v.Content.Value
+	case openai.ChatCompletionMessageParam:
+		switch content := v.Content.Value.(type) {
+		case string:
+			return content
+		case []openai.ChatCompletionContentPartTextParam:
+			for _, part := range content {
+				texts = append(texts, part.Text.Value)
+			}
+		case []openai.ChatCompletionContentPartUnionParam:
+			for _, part := range content {
+				if text, ok := part.(openai.ChatCompletionContentPartTextParam); ok {
+					texts = append(texts, text.Text.Value)
+				}
+			}
+		case []openai.ChatCompletionAssistantMessageParamContentUnion:
+			for _, part := range content {
+				if text, ok := part.(openai.ChatCompletionContentPartTextParam); ok {
+					texts = append(texts, text.Text.Value)
+				}
+			}
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// ToolCalls returns the tool calls requested by an assistant message.
+func ToolCalls(msg openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageToolCall {
+	switch v := msg.(type) {
+	case openai.ChatCompletionMessage:
+		return v.ToolCalls
+	case openai.ChatCompletionAssistantMessageParam:
+		return toolCallsFromParams(v.ToolCalls.Value)
+	case openai.ChatCompletionMessageParam:
+		switch calls := v.ToolCalls.Value.(type) {
+		case []openai.ChatCompletionMessageToolCall:
+			return calls
+		case []openai.ChatCompletionMessageToolCallParam:
+			return toolCallsFromParams(calls)
+		}
+	}
+	return nil
+}
+
+func toolCallsFromParams(params []openai.ChatCompletionMessageToolCallParam) []openai.ChatCompletionMessageToolCall {
+	if len(params) == 0 {
+		return nil
+	}
+
+	calls := make([]openai.ChatCompletionMessageToolCall, len(params))
+	for i, p := range params {
+		calls[i] = openai.ChatCompletionMessageToolCall{
+			ID:   p.ID.Value,
+			Type: p.Type.Value,
+			Function: openai.ChatCompletionMessageToolCallFunction{
+				Name:      p.Function.Value.Name.Value,
+				Arguments: p.Function.Value.Arguments.Value,
+			},
+		}
+	}
+	return calls
+}
+
+// ToolCallID returns the ID of the tool call a tool message answers.
+func ToolCallID(msg openai.ChatCompletionMessageParamUnion) string {
+	switch v := msg.(type) {
+	case openai.ChatCompletionToolMessageParam:
+		return v.ToolCallID.Value
+	case openai.ChatCompletionMessageParam:
+		return v.ToolCallID.Value
+	}
+	return ""
+}
+
+// MapText returns a copy of the message with f applied to every text it carries: the content,
+// the text parts and the arguments of tool and function calls. Other parts are kept as they are.
+func MapText(msg openai.ChatCompletionMessageParamUnion, f func(string) string) openai.ChatCompletionMessageParamUnion {
+	switch v := msg.(type) {
+	case openai.ChatCompletionMessage:
+		v.Content = f(v.Content)
+		if len(v.ToolCalls) > 0 {
+			calls := make([]openai.ChatCompletionMessageToolCall, len(v.ToolCalls))
+			for i, call := range v.ToolCalls {
+				call.Function.Arguments = f(call.Function.Arguments)
+				calls[i] = call
+			}
+			v.ToolCalls = calls
+		}
+		v.FunctionCall.Arguments = f(v.FunctionCall.Arguments)
+		return v
+	case openai.ChatCompletionSystemMessageParam:
+		v.Content = openai.F(mapTextParts(v.Content.Value, f))
+		return v
+	case openai.ChatCompletionUserMessageParam:
+		parts := make([]openai.ChatCompletionContentPartUnionParam, len(v.Content.Value))
+		for i, part := range v.Content.Value {
+			if text, ok := part.(openai.ChatCompletionContentPartTextParam); ok {
+				text.Text = openai.F(f(text.Text.Value))
+				part = text
+			}
+			parts[i] = part
+		}
+		v.Content = openai.F(parts)
+		return v
+	case openai.ChatCompletionAssistantMessageParam:
+		if v.Content.Present {
+			parts := make([]openai.ChatCompletionAssistantMessageParamContentUnion, len(v.Content.Value))
+			for i, part := range v.Content.Value {
+				if text, ok := part.(openai.ChatCompletionContentPartTextParam); ok {
+					text.Text = openai.F(f(text.Text.Value))
+					part = text
+				}
+				parts[i] = part
+			}
+			v.Content = openai.F(parts)
+		}
+		if v.ToolCalls.Present {
+			v.ToolCalls = openai.F(mapToolCallParams(v.ToolCalls.Value, f))
+		}
+		if v.FunctionCall.Present {
+			call := v.FunctionCall.Value
+			call.Arguments = openai.F(f(call.Arguments.Value))
+			v.FunctionCall = openai.F(call)
+		}
+		return v
+	case openai.ChatCompletionToolMessageParam:
+		v.Content = openai.F(mapTextParts(v.Content.Value, f))
+		return v
+	case openai.ChatCompletionFunctionMessageParam:
+		v.Content = openai.F(f(v.Content.Value))
+		return v
+	case openai.ChatCompletionMessageParam:
+		switch content := v.Content.Value.(type) {
+		case string:
+			v.Content = openai.F[any](f(content))
+		case []openai.ChatCompletionContentPartTextParam:
+			v.Content = openai.F[any](mapTextParts(content, f))
+		}
+		switch calls := v.ToolCalls.Value.(type) {
+		case []openai.ChatCompletionMessageToolCallParam:
+			v.ToolCalls = openai.F[any](mapToolCallParams(calls, f))
+		case []openai.ChatCompletionMessageToolCall:
+			mapped := make([]openai.ChatCompletionMessageToolCall, len(calls))
+			for i, call := range calls {
+				call.Function.Arguments = f(call.Function.Arguments)
+				mapped[i] = call
+			}
+			v.ToolCalls = openai.F[any](mapped)
+		}
+		return v
+	}
+	return msg
+}
+
+func mapTextParts(parts []openai.ChatCompletionContentPartTextParam, f func(string) string) []openai.ChatCompletionContentPartTextParam {
+	mapped := make([]openai.ChatCompletionContentPartTextParam, len(parts))
+	for i, part := range parts {
+		part.Text = openai.F(f(part.Text.Value))
+		mapped[i] = part
+	}
+	return mapped
+}
+
+func mapToolCallParams(calls []openai.ChatCompletionMessageToolCallParam, f func(string) string) []openai.ChatCompletionMessageToolCallParam {
+	mapped := make([]openai.ChatCompletionMessageToolCallParam, len(calls))
+	for i, call := range calls {
+		function := call.Function.Value
+		function.Arguments = openai.F(f(function.Arguments.Value))
+		call.Function = openai.F(function)
+		mapped[i] = call
+	}
+	return mapped
+}
+
+// PendingToolCalls returns the tool calls of the last assistant message that are not answered
+// by a tool message yet.
+func PendingToolCalls(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageToolCall {
+	blocks := Blocks(history)
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	block := blocks[len(blocks)-1]
+	answered := map[string]bool{}
+	for _, msg := range block[1:] {
+		answered[ToolCallID(msg)] = true
+	}
+
+	var pending []openai.ChatCompletionMessageToolCall
+	for _, call := range ToolCalls(block[0]) {
+		if !answered[call.ID] {
+			pending = append(pending, call)
+		}
+	}
+	return pending
+}
+
+// Blocks splits the history into units that must be kept or dropped together.
+// An assistant message with tool calls, the tool messages answering it and the attachments of their
+// results form a single block, every other message is a block of its own.
+func Blocks(history []openai.ChatCompletionMessageParamUnion) [][]openai.ChatCompletionMessageParamUnion {
+	var blocks [][]openai.ChatCompletionMessageParamUnion
+
+	for i := 0; i < len(history); {
+		end := i + 1
+		if len(ToolCalls(history[i])) > 0 {
+			for end < len(history) && (Role(history[end]) == "tool" || IsAttachments(history[end])) {
+				end++
+			}
+		}
+		blocks = append(blocks, history[i:end])
+		i = end
+	}
+
+	return blocks
+}
+
+// Flatten joins blocks back into a single history slice.
+func Flatten(blocks [][]openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	history := []openai.ChatCompletionMessageParamUnion{}
+	for _, block := range blocks {
+		history = append(history, block...)
+	}
+	return history
+}
+
+// SummaryName is the participant name that marks a system message as a summary of earlier turns.
+const SummaryName = "history_summary"
+
+// NewSummary creates the system message that stands in for summarised turns.
+func NewSummary(text string) openai.ChatCompletionMessageParamUnion {
+	return openai.ChatCompletionSystemMessageParam{
+		Role: openai.F(openai.ChatCompletionSystemMessageParamRoleSystem),
+		Name: openai.F(SummaryName),
+		Content: openai.F([]openai.ChatCompletionContentPartTextParam{
+			openai.TextPart("Summary of the earlier conversation:\n" + text),
+		}),
+	}
+}
+
+// IsSummary reports whether the message was created by NewSummary.
+func IsSummary(msg openai.ChatCompletionMessageParamUnion) bool {
+	v, ok := msg.(openai.ChatCompletionSystemMessageParam)
+	return ok && v.Name.Value == SummaryName
+}
+
+// AttachmentsName is the participant name that marks a user message carrying the attachments of tool results.
+const AttachmentsName = "tool_attachments"
+
+// IsAttachments reports whether the message carries the attachments of tool results. Such messages
+// belong to the tool calls before them and do not start a turn.
+func IsAttachments(msg openai.ChatCompletionMessageParamUnion) bool {
+	v, ok := msg.(openai.ChatCompletionUserMessageParam)
+	return ok && v.Name.Value == AttachmentsName
+}
+
+// StartsTurn reports whether the message is a user message that starts a turn of the conversation.
+func StartsTurn(msg openai.ChatCompletionMessageParamUnion) bool {
+	return Role(msg) == "user" && !IsAttachments(msg)
+}
+
+// Transcript renders the messages as plain text, one "role: content" line per message.
+func Transcript(history []openai.ChatCompletionMessageParamUnion) string {
+	var sb strings.Builder
+
+	for _, msg := range history {
+		if text := Text(msg); text != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", Role(msg), text)
+		}
+		for _, call := range ToolCalls(msg) {
+			fmt.Fprintf(&sb, "%s: called %s(%s)\n", Role(msg), call.Function.Name, call.Function.Arguments)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,153 @@
+package history
+
+import (
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// StrategyFunc adapts an ordinary function to the types.HistoryStrategy interface.
+type StrategyFunc func(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion
+
+func (f StrategyFunc) Apply(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	return f(history)
+}
+
+// Counter returns the number of tokens a message occupies in the model context.
+type Counter func(msg openai.ChatCompletionMessageParamUnion) int
+
+// EstimateTokens is a rough Counter that assumes four characters per token, see MediaTokens for images and audio.
+func EstimateTokens(msg openai.ChatCompletionMessageParamUnion) int {
+	chars := len(Text(msg))
+	for _, call := range ToolCalls(msg) {
+		chars += len(call.Function.Name) + len(call.Function.Arguments)
+	}
+	tokens := chars/4 + 4
+	for _, part := range Media(msg) {
+		tokens += MediaTokens(part)
+	}
+	return tokens
+}
+
+// KeepLastTurns keeps the last n turns of the conversation. A turn starts with a user message,
+// see StartsTurn.
+func KeepLastTurns(n int) types.HistoryStrategy {
+	return StrategyFunc(func(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+		if n <= 0 {
+			return history
+		}
+
+		turns := 0
+		for i := len(history) - 1; i >= 0; i-- {
+			if !StartsTurn(history[i]) {
+				continue
+			}
+			if turns++; turns == n {
+				return history[i:]
+			}
+		}
+
+		return history
+	})
+}
+
+// TokenWindow drops the oldest messages until the history fits into budget tokens.
+// The most recent block is always kept, even if it alone exceeds the budget.
+func TokenWindow(budget int, counter Counter) types.HistoryStrategy {
+	if counter == nil {
+		counter = EstimateTokens
+	}
+
+	return StrategyFunc(func(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+		blocks := Blocks(history)
+
+		total := 0
+		start := len(blocks)
+		for i := len(blocks) - 1; i >= 0; i-- {
+			size := 0
+			for _, msg := range blocks[i] {
+				size += counter(msg)
+			}
+			if total+size > budget && i < len(blocks)-1 {
+				break
+			}
+			total += size
+			start = i
+		}
+
+		if start == 0 {
+			return history
+		}
+		return Flatten(blocks[start:])
+	})
+}
+
+// PinFirstUserMessage applies inner to the history after the first user message and keeps that
+// message in front of the result whenever inner drops something. The pinned message is not
+// counted against the limits of inner.
+func PinFirstUserMessage(inner types.HistoryStrategy) types.HistoryStrategy {
+	return StrategyFunc(func(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+		first := -1
+		for i, msg := range history {
+			if StartsTurn(msg) {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			return inner.Apply(history)
+		}
+
+		rest := history[first+1:]
+		kept := inner.Apply(rest)
+		if len(kept) == len(rest) {
+			return history
+		}
+
+		result := []openai.ChatCompletionMessageParamUnion{history[first]}
+		return append(result, kept...)
+	})
+}
+
+// Chain applies the strategies in order, each one to the output of the previous.
+func Chain(strategies ...types.HistoryStrategy) types.HistoryStrategy {
+	return StrategyFunc(func(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+		for _, s := range strategies {
+			history = s.Apply(history)
+		}
+		return history
+	})
+}
+
+// StripToolCalls drops the tool and function call messages and their results. Assistant messages
+// that called tools and also had text are kept as plain assistant messages.
+func StripToolCalls() types.HistoryStrategy {
+	return StrategyFunc(func(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+		result := []openai.ChatCompletionMessageParamUnion{}
+		for _, msg := range history {
+			switch Role(msg) {
+			case "tool", "function":
+				continue
+			case "assistant":
+				if len(ToolCalls(msg)) > 0 || isFunctionCall(msg) {
+					if text := Text(msg); text != "" {
+						result = append(result, openai.AssistantMessage(text))
+					}
+					continue
+				}
+			}
+			result = append(result, msg)
+		}
+		return result
+	})
+}
+
+func isFunctionCall(msg openai.ChatCompletionMessageParamUnion) bool {
+	switch v := msg.(type) {
+	case openai.ChatCompletionMessage:
+		return v.FunctionCall.Name != ""
+	case openai.ChatCompletionAssistantMessageParam:
+		return v.FunctionCall.Present
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package history_test
+
+import (
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+)
+
+func toolCallMessage(id string) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role: openai.ChatCompletionMessageRoleAssistant,
+		ToolCalls: []openai.ChatCompletionMessageToolCall{{
+			ID:       id,
+			Type:     openai.ChatCompletionMessageToolCallTypeFunction,
+			Function: openai.ChatCompletionMessageToolCallFunction{Name: "lookup", Arguments: "{}"},
+		}},
+	}
+}
+
+func sampleHistory() []openai.ChatCompletionMessageParamUnion {
+	return []openai.ChatCompletionMessageParamUnion{
+		openai.UserMessage("first question"),
+		openai.AssistantMessage("first answer"),
+		openai.UserMessage("second question"),
+		toolCallMessage("call_1"),
+		openai.ToolMessage("call_1", "a rather long tool result that takes up many tokens in the window"),
+		openai.AssistantMessage("second answer"),
+		openai.UserMessage("third question"),
+		openai.AssistantMessage("third answer"),
+	}
+}
+
+// checkPairs verifies that every tool message directly follows the assistant message that called it.
+func checkPairs(t *testing.T, msgs []openai.ChatCompletionMessageParamUnion) {
+	t.Helper()
+
+	pending := map[string]bool{}
+	for _, msg := range msgs {
+		if history.Role(msg) == "tool" {
+			if !pending[history.ToolCallID(msg)] {
+				t.Fatalf("tool result %s separated from its tool call", history.ToolCallID(msg))
+			}
+			continue
+		}
+		pending = map[string]bool{}
+		for _, call := range history.ToolCalls(msg) {
+			pending[call.ID] = true
+		}
+	}
+}
+
+func TestKeepLastTurns(t *testing.T) {
+	msgs := history.KeepLastTurns(2).Apply(sampleHistory())
+
+	if len(msgs) != 6 {
+		t.Fatalf("expected 6 messages, got %d", len(msgs))
+	}
+	if history.Text(msgs[0]) != "second question" {
+		t.Fatalf("unexpected first message: %q", history.Text(msgs[0]))
+	}
+	checkPairs(t, msgs)
+}
+
+func TestTokenWindowKeepsToolPairs(t *testing.T) {
+	for budget := 0; budget < 80; budget++ {
+		msgs := history.TokenWindow(budget, nil).Apply(sampleHistory())
+		if len(msgs) == 0 {
+			t.Fatalf("budget %d: expected the last message to be kept", budget)
+		}
+		checkPairs(t, msgs)
+	}
+}
+
+func TestPinFirstUserMessage(t *testing.T) {
+	msgs := history.PinFirstUserMessage(history.KeepLastTurns(1)).Apply(sampleHistory())
+
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+	if history.Text(msgs[0]) != "first question" || history.Text(msgs[1]) != "third question" {
+		t.Fatalf("unexpected messages: %q, %q", history.Text(msgs[0]), history.Text(msgs[1]))
+	}
+}
+
+func TestStripToolCalls(t *testing.T) {
+	msgs := history.StripToolCalls().Apply(sampleHistory())
+
+	if len(msgs) != 6 {
+		t.Fatalf("expected 6 messages, got %d", len(msgs))
+	}
+	for _, msg := range msgs {
+		if history.Role(msg) == "tool" || len(history.ToolCalls(msg)) > 0 {
+			t.Fatalf("unexpected tool message: %+v", msg)
+		}
+	}
+}
+
+func TestAttachmentsDoNotStartTurns(t *testing.T) {
+	attachments := openai.UserMessageParts(openai.TextPart("Attachments returned by the tool call call_1 (lookup):"))
+	attachments.Name = openai.F(history.AttachmentsName)
+
+	msgs := []openai.ChatCompletionMessageParamUnion{
+		openai.UserMessage("first question"),
+		openai.AssistantMessage("first answer"),
+		openai.UserMessage("second question"),
+		toolCallMessage("call_1"),
+		openai.ToolMessage("call_1", "result"),
+		attachments,
+		openai.AssistantMessage("second answer"),
+	}
+
+	if kept := history.KeepLastTurns(1).Apply(msgs); len(kept) != 5 || history.Text(kept[0]) != "second question" {
+		t.Fatalf("expected the last turn to start with the question, got %d messages", len(kept))
+	}
+	if blocks := history.Blocks(msgs); len(blocks) != 5 || len(blocks[3]) != 3 {
+		t.Fatalf("expected the attachments in the block of the tool call, got %d blocks", len(blocks))
+	}
+
+	// a history that starts in the middle of a tool call, as after a handoff filter
+	tail := append(msgs[3:len(msgs):len(msgs)], openai.UserMessage("third question"), openai.AssistantMessage("third answer"))
+	if pinned := history.PinFirstUserMessage(history.KeepLastTurns(1)).Apply(tail); len(pinned) != len(tail) {
+		t.Fatalf("expected the attachments not to be pinned as the first user message, got %d messages", len(pinned))
+	}
+}
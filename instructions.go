@@ -0,0 +1,104 @@
+package goswarm
+
+import (
+	"fmt"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// ParseInstructions parses text/template instructions for option.WithAgentInstructions. The template
+// sees the context variables as .Vars, the agent name as .Agent and the current time as .Now and .Date.
+// Templates included with {{template "name" .}} must be defined in the partials.
+func ParseInstructions(text string, opts ...option.TemplateOption) (*types.InstructionsTemplate, error) {
+	options := option.DefTemplateOptions
+	for _, o := range opts {
+		o.ApplyOption(&options)
+	}
+
+	tmpl := template.New("instructions")
+	if options.Partials != nil {
+		partials, err := options.Partials.Clone()
+		if err != nil {
+			return nil, err
+		}
+		tmpl = partials.New("instructions")
+	}
+	if options.Funcs != nil {
+		tmpl = tmpl.Funcs(options.Funcs)
+	}
+	if options.Strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIncludes(tmpl, tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+
+	return &types.InstructionsTemplate{Template: tmpl}, nil
+}
+
+// MustParseInstructions is like ParseInstructions but panics on errors, for agents built in
+// package variables.
+func MustParseInstructions(text string, opts ...option.TemplateOption) *types.InstructionsTemplate {
+	tmpl, err := ParseInstructions(text, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+// checkIncludes reports templates that are included but not defined.
+func checkIncludes(tmpl *template.Template, node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.TemplateNode:
+		if tmpl.Lookup(n.Name) == nil {
+			return fmt.Errorf("template %q is not defined", n.Name)
+		}
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkIncludes(tmpl, child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkBranch(tmpl, &n.BranchNode)
+	case *parse.RangeNode:
+		return checkBranch(tmpl, &n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(tmpl, &n.BranchNode)
+	}
+	return nil
+}
+
+func checkBranch(tmpl *template.Template, branch *parse.BranchNode) error {
+	if err := checkIncludes(tmpl, branch.List); err != nil {
+		return err
+	}
+	return checkIncludes(tmpl, branch.ElseList)
+}
+
+// executeInstructions renders templated instructions for the agent.
+func executeInstructions(ctx Context, agent *types.Agent, tmpl *types.InstructionsTemplate) (string, error) {
+	now := time.Now()
+	text, err := tmpl.Execute(types.InstructionsData{
+		Agent: agent.Name,
+		Vars:  ctx.GetVariables(),
+		Now:   now,
+		Date:  now.Format("2006-01-02"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("instructions of agent %q: %w", agent.Name, err)
+	}
+	return text, nil
+}
@@ -0,0 +1,54 @@
+package goswarm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+)
+
+func TestInstructionsTemplate(t *testing.T) {
+	var prompts []string
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		prompts = append(prompts, messageText(req.Messages[0]))
+		return assistantReply("Hi!")
+	})
+
+	partials := template.Must(template.New("").Parse(`{{define "tone"}}Be brief.{{end}}`))
+
+	agent := goswarm.NewAgent(
+		option.WithAgentName("Greeter"),
+		option.WithAgentInstructions(goswarm.MustParseInstructions(
+			`You are {{.Agent}}. Greet {{.Vars.name}} on {{.Date}}. {{template "tone" .}}`,
+			option.WithTemplatePartials(partials),
+			option.WithTemplateStrict(true),
+		)),
+	)
+	messages := goswarm.NewMessages(openai.UserMessage("Hi!"))
+
+	ctx := goswarm.NewContext(context.Background())
+	ctx.SetVariable("name", "James")
+	resp := client.Run(ctx, agent, messages)
+
+	if resp.Error != nil || !strings.HasPrefix(prompts[0], "You are Greeter. Greet James on ") || !strings.HasSuffix(prompts[0], " Be brief.") {
+		t.Fatalf("unexpected instructions: %q (%v)", prompts[0], resp.Error)
+	}
+
+	resp = client.Run(goswarm.NewContext(context.Background()), agent, messages)
+	if resp.Error == nil || len(prompts) != 1 {
+		t.Fatalf("expected the missing variable to fail the run, got %v", resp.Error)
+	}
+
+	if _, err := goswarm.ParseInstructions(`{{template "missing" .}}`, option.WithTemplatePartials(partials)); err == nil {
+		t.Fatal("expected the undefined partial to be reported when parsing")
+	}
+	if _, err := goswarm.ParseInstructions(`{{.Vars.name`); err == nil {
+		t.Fatal("expected the syntax error to be reported when parsing")
+	}
+}
@@ -0,0 +1,363 @@
+package goswarm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// chatModels are the chat models the OpenAI SDK knows, option.WithLoadModels adds others.
+var chatModels = []openai.ChatModel{
+	openai.ChatModelO1Preview,
+	openai.ChatModelO1Preview2024_09_12,
+	openai.ChatModelO1Mini,
+	openai.ChatModelO1Mini2024_09_12,
+	openai.ChatModelGPT4o,
+	openai.ChatModelGPT4o2024_08_06,
+	openai.ChatModelGPT4o2024_05_13,
+	openai.ChatModelGPT4oRealtimePreview,
+	openai.ChatModelGPT4oRealtimePreview2024_10_01,
+	openai.ChatModelGPT4oAudioPreview,
+	openai.ChatModelGPT4oAudioPreview2024_10_01,
+	openai.ChatModelChatgpt4oLatest,
+	openai.ChatModelGPT4oMini,
+	openai.ChatModelGPT4oMini2024_07_18,
+	openai.ChatModelGPT4Turbo,
+	openai.ChatModelGPT4Turbo2024_04_09,
+	openai.ChatModelGPT4_0125Preview,
+	openai.ChatModelGPT4TurboPreview,
+	openai.ChatModelGPT4_1106Preview,
+	openai.ChatModelGPT4VisionPreview,
+	openai.ChatModelGPT4,
+	openai.ChatModelGPT4_0314,
+	openai.ChatModelGPT4_0613,
+	openai.ChatModelGPT4_32k,
+	openai.ChatModelGPT4_32k0314,
+	openai.ChatModelGPT4_32k0613,
+	openai.ChatModelGPT3_5Turbo,
+	openai.ChatModelGPT3_5Turbo16k,
+	openai.ChatModelGPT3_5Turbo0301,
+	openai.ChatModelGPT3_5Turbo0613,
+	openai.ChatModelGPT3_5Turbo1106,
+	openai.ChatModelGPT3_5Turbo0125,
+	openai.ChatModelGPT3_5Turbo16k0613,
+}
+
+// located is a value of an agent definition along with the line it was read from.
+type located[T any] struct {
+	Value T
+	Line  int
+}
+
+func (l *located[T]) UnmarshalYAML(n *yaml.Node) error {
+	l.Line = n.Line
+	return n.Decode(&l.Value)
+}
+
+type agentsFile struct {
+	Agents []agentConfig `yaml:"agents"`
+}
+
+func (f *agentsFile) UnmarshalYAML(n *yaml.Node) error {
+	if err := checkFields(n, *f); err != nil {
+		return err
+	}
+	type plain agentsFile
+	return n.Decode((*plain)(f))
+}
+
+type agentConfig struct {
+	line int
+
+	Name              located[string]   `yaml:"name"`
+	Model             located[string]   `yaml:"model"`
+	Instructions      located[string]   `yaml:"instructions"`
+	InstructionsFile  located[string]   `yaml:"instructions_file"`
+	Tools             []located[string] `yaml:"tools"`
+	Handoffs          []handoffConfig   `yaml:"handoffs"`
+	ToolChoice        located[string]   `yaml:"tool_choice"`
+	ParallelToolCalls *bool             `yaml:"parallel_tool_calls"`
+
+	Temperature      *located[float64] `yaml:"temperature"`
+	TopP             *located[float64] `yaml:"top_p"`
+	MaxTokens        *located[int64]   `yaml:"max_tokens"`
+	Seed             *int64            `yaml:"seed"`
+	Stop             []string          `yaml:"stop"`
+	PresencePenalty  *located[float64] `yaml:"presence_penalty"`
+	FrequencyPenalty *located[float64] `yaml:"frequency_penalty"`
+	User             string            `yaml:"user"`
+	Metadata         map[string]string `yaml:"metadata"`
+}
+
+func (c *agentConfig) UnmarshalYAML(n *yaml.Node) error {
+	if err := checkFields(n, *c); err != nil {
+		return err
+	}
+	c.line = n.Line
+	type plain agentConfig
+	return n.Decode((*plain)(c))
+}
+
+// handoffConfig is either the name of the target agent or a mapping with the handoff options.
+type handoffConfig struct {
+	line int
+
+	Agent       string `yaml:"agent"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+func (c *handoffConfig) UnmarshalYAML(n *yaml.Node) error {
+	c.line = n.Line
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&c.Agent)
+	}
+	if err := checkFields(n, *c); err != nil {
+		return err
+	}
+	type plain handoffConfig
+	return n.Decode((*plain)(c))
+}
+
+// checkFields reports the keys of the mapping that are not fields of v.
+func checkFields(n *yaml.Node, v any) error {
+	if n.Kind != yaml.MappingNode {
+		return nil // Decode reports the type error
+	}
+
+	fields := map[string]bool{}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("yaml"); tag != "" {
+			fields[strings.Split(tag, ",")[0]] = true
+		}
+	}
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key := n.Content[i]
+		if !fields[key.Value] {
+			return &types.ConfigError{Line: key.Line, Msg: fmt.Sprintf("unknown field %q", key.Value)}
+		}
+	}
+	return nil
+}
+
+// LoadAgentsFile loads the agent definitions of a YAML or JSON file, see LoadAgents.
+func LoadAgentsFile(name string, opts ...option.LoadOption) (*types.Registry, error) {
+	return LoadAgents(os.DirFS(filepath.Dir(name)), filepath.Base(name), opts...)
+}
+
+// LoadAgents builds the agents defined in a YAML or JSON file of fsys, e.g. an embed.FS.
+// Tools are referred to by their name in option.WithLoadTools, handoffs by the name of the
+// target agent. Instructions are templates, see ParseInstructions, and instructions_file is
+// read relative to the file. Every problem found is returned, as *types.ConfigError when it
+// can be located.
+func LoadAgents(fsys fs.FS, name string, opts ...option.LoadOption) (*types.Registry, error) {
+	options := option.DefLoadOptions
+	for _, o := range opts {
+		o.ApplyOption(&options)
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var file agentsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		var cerr *types.ConfigError
+		if errors.As(err, &cerr) {
+			cerr.File = name
+			return nil, cerr
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(file.Agents) == 0 {
+		return nil, &types.ConfigError{File: name, Line: 1, Msg: "no agents defined"}
+	}
+
+	l := &loader{fsys: fsys, file: name, options: options}
+	return l.load(file.Agents)
+}
+
+type loader struct {
+	fsys    fs.FS
+	file    string
+	options option.LoadOptions
+	errs    []error
+}
+
+func (l *loader) errorf(line int, format string, args ...any) {
+	l.errs = append(l.errs, &types.ConfigError{File: l.file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *loader) load(configs []agentConfig) (*types.Registry, error) {
+	agents := make([]*types.Agent, len(configs))
+	byName := map[string]*types.Agent{}
+
+	for i, config := range configs {
+		name := config.Name.Value
+		switch {
+		case name == "":
+			l.errorf(config.line, "agent without a name")
+		case byName[name] != nil:
+			l.errorf(config.Name.Line, "duplicate agent %q", name)
+		}
+
+		agents[i] = l.buildAgent(config)
+		if name != "" && byName[name] == nil {
+			byName[name] = agents[i]
+		}
+	}
+
+	// the targets of the handoffs exist once every agent is built
+	for i, config := range configs {
+		for _, handoff := range config.Handoffs {
+			target := byName[handoff.Agent]
+			if target == nil {
+				l.errorf(handoff.line, "handoff to unknown agent %q", handoff.Agent)
+				continue
+			}
+			agents[i].Functions = append(agents[i].Functions, NewHandoff(target,
+				option.WithHandoffName(handoff.Name),
+				option.WithHandoffDescription(handoff.Description),
+			))
+		}
+	}
+
+	if len(l.errs) > 0 {
+		return nil, errors.Join(l.errs...)
+	}
+
+	registry, err := NewRegistry(agents...)
+	if err != nil {
+		return nil, err
+	}
+	return registry, registry.Validate()
+}
+
+func (l *loader) buildAgent(config agentConfig) *types.Agent {
+	opts := []option.AgentOption{option.WithAgentName(config.Name.Value)}
+
+	if model := config.Model.Value; model != "" {
+		if !l.knownModel(model) {
+			l.errorf(config.Model.Line, "unknown model %q", model)
+		}
+		opts = append(opts, option.WithAgentModel(model))
+	}
+
+	if instructions, ok := l.instructions(config); ok {
+		opts = append(opts, option.WithAgentInstructions(instructions))
+	}
+
+	switch choice := option.AgentToolChoiceOption(config.ToolChoice.Value); choice {
+	case "":
+	case option.ToolChoiceOptionNone, option.ToolChoiceOptionAuto, option.ToolChoiceOptionRequired:
+		opts = append(opts, option.WithAgentToolChoice(choice))
+	default:
+		l.errorf(config.ToolChoice.Line, "invalid tool_choice %q, expected none, auto or required", choice)
+	}
+	if config.ParallelToolCalls != nil {
+		opts = append(opts, option.WithAgentParallelToolCalls(*config.ParallelToolCalls))
+	}
+
+	opts = append(opts, option.WithAgentModelSettings(l.settings(config)))
+
+	agent := NewAgent(opts...)
+	for _, tool := range config.Tools {
+		fn, ok := l.options.Tools[tool.Value]
+		if !ok {
+			l.errorf(tool.Line, "unknown tool %q", tool.Value)
+			continue
+		}
+		if fn == nil {
+			l.errorf(tool.Line, "tool %q is nil", tool.Value)
+			continue
+		}
+		if _, isAgent := fn.(*types.AgentTool); !isAgent && reflect.TypeOf(fn).Kind() != reflect.Func {
+			l.errorf(tool.Line, "tool %q is not a function", tool.Value)
+			continue
+		}
+		agent.Functions = append(agent.Functions, fn)
+	}
+	return agent
+}
+
+// knownModel checks the model against the chat models of the OpenAI SDK and option.WithLoadModels.
+func (l *loader) knownModel(model string) bool {
+	return slices.Contains(chatModels, model) || slices.Contains(l.options.Models, model)
+}
+
+// instructions parses the inline or the file instructions, false when there are none.
+func (l *loader) instructions(config agentConfig) (*types.InstructionsTemplate, bool) {
+	text, line := config.Instructions.Value, config.Instructions.Line
+
+	if file := config.InstructionsFile.Value; file != "" {
+		if text != "" {
+			l.errorf(config.InstructionsFile.Line, "both instructions and instructions_file are set")
+			return nil, false
+		}
+
+		data, err := fs.ReadFile(l.fsys, path.Join(path.Dir(l.file), file))
+		if err != nil {
+			l.errorf(config.InstructionsFile.Line, "read instructions: %v", err)
+			return nil, false
+		}
+		text, line = string(data), config.InstructionsFile.Line
+	}
+	if text == "" {
+		return nil, false
+	}
+
+	tmpl, err := ParseInstructions(text, l.options.Templates...)
+	if err != nil {
+		l.errorf(line, "invalid instructions: %v", err)
+		return nil, false
+	}
+	return tmpl, true
+}
+
+func (l *loader) settings(config agentConfig) types.ModelSettings {
+	settings := types.ModelSettings{
+		Seed:     config.Seed,
+		Stop:     config.Stop,
+		User:     config.User,
+		Metadata: config.Metadata,
+	}
+
+	settings.Temperature = l.float(config.Temperature, "temperature", 0, 2)
+	settings.TopP = l.float(config.TopP, "top_p", 0, 1)
+	settings.PresencePenalty = l.float(config.PresencePenalty, "presence_penalty", -2, 2)
+	settings.FrequencyPenalty = l.float(config.FrequencyPenalty, "frequency_penalty", -2, 2)
+
+	if config.MaxTokens != nil {
+		if config.MaxTokens.Value <= 0 {
+			l.errorf(config.MaxTokens.Line, "max_tokens must be positive")
+		}
+		settings.MaxTokens = &config.MaxTokens.Value
+	}
+	return settings
+}
+
+// float returns the value when it is set, reporting values outside [lo, hi].
+func (l *loader) float(v *located[float64], name string, lo, hi float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	if v.Value < lo || v.Value > hi {
+		l.errorf(v.Line, "%s must be between %g and %g", name, lo, hi)
+	}
+	return &v.Value
+}
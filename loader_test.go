@@ -0,0 +1,147 @@
+package goswarm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+const agentsYAML = `agents:
+  - name: Triage Agent
+    model: gpt-4o
+    instructions: Help {{.Vars.name}} with the request.
+    temperature: 0.2
+    handoffs:
+      - Refunds Agent
+      - agent: Sales Agent
+        description: Buy something.
+  - name: Refunds Agent
+    instructions_file: prompts/refunds.tmpl
+    tools: [refund_order]
+    tool_choice: required
+  - name: Sales Agent
+    model: gpt-4o-mini
+    max_tokens: 200
+    stop: ["END"]
+`
+
+const agentsJSON = `{
+	"agents": [
+		{"name": "Triage Agent", "handoffs": ["Refunds Agent"]},
+		{"name": "Refunds Agent", "tools": ["refund_order"], "top_p": 0.5}
+	]
+}`
+
+func TestLoadAgents(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/agents.yaml":          {Data: []byte(agentsYAML)},
+		"config/agents.json":          {Data: []byte(agentsJSON)},
+		"config/prompts/refunds.tmpl": {Data: []byte("You are {{.Agent}}.")},
+	}
+	tools := option.WithLoadTools(map[string]types.AgentFunction{"refund_order": refundOrder})
+
+	registry, err := goswarm.LoadAgents(fsys, "config/agents.yaml", tools)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	triage, _ := registry.Get("Triage Agent")
+	if len(triage.Functions) != 2 || triage.Functions[1].(*types.Handoff).Description != "Buy something." {
+		t.Fatalf("expected two handoffs, got %+v", triage.Functions)
+	}
+	if *triage.ModelSettings.Temperature != 0.2 {
+		t.Fatalf("expected the temperature, got %+v", triage.ModelSettings)
+	}
+	text, _ := triage.Instructions.(*types.InstructionsTemplate).Execute(types.InstructionsData{Vars: types.ContextVariables{"name": "Jane"}})
+	if text != "Help Jane with the request." {
+		t.Fatalf("unexpected instructions: %q", text)
+	}
+
+	refunds, _ := registry.Get("Refunds Agent")
+	text, _ = refunds.Instructions.(*types.InstructionsTemplate).Execute(types.InstructionsData{Agent: refunds.Name})
+	if text != "You are Refunds Agent." || len(refunds.Functions) != 1 || refunds.Model != "gpt-4o" {
+		t.Fatalf("unexpected refunds agent: %+v", refunds)
+	}
+
+	sales, _ := registry.Get("Sales Agent")
+	if *sales.ModelSettings.MaxTokens != 200 || sales.ModelSettings.Stop[0] != "END" {
+		t.Fatalf("expected the sampling parameters, got %+v", sales.ModelSettings)
+	}
+
+	registry, err = goswarm.LoadAgents(fsys, "config/agents.json", tools)
+	if err != nil {
+		t.Fatalf("load of the JSON file failed: %v", err)
+	}
+	if len(registry.Names()) != 2 {
+		t.Fatalf("expected two agents, got %v", registry.Names())
+	}
+}
+
+func TestLoadAgentsErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"agents.yaml": {Data: []byte(`agents:
+  - name: Triage Agent
+    model: gpt-5-turbo-ultra
+    tools:
+      - lookup_order
+    handoffs: [Billing Agent]
+  - name: Triage Agent
+`)},
+		"typo.yaml": {Data: []byte(`agents:
+  - name: Triage Agent
+    temprature: 0.2
+`)},
+	}
+
+	_, err := goswarm.LoadAgents(fsys, "agents.yaml", option.WithLoadModels("gpt-4o"))
+	for _, want := range []string{
+		`agents.yaml:3: unknown model "gpt-5-turbo-ultra"`,
+		`agents.yaml:5: unknown tool "lookup_order"`,
+		`agents.yaml:6: handoff to unknown agent "Billing Agent"`,
+		`agents.yaml:7: duplicate agent "Triage Agent"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+
+	_, err = goswarm.LoadAgents(fsys, "typo.yaml")
+	var cerr *types.ConfigError
+	if !errors.As(err, &cerr) || cerr.Line != 3 || !strings.Contains(cerr.Msg, "temprature") {
+		t.Fatalf("expected the unknown field to be reported, got %v", err)
+	}
+}
+
+func TestLoadAgentsModelsAndTools(t *testing.T) {
+	fsys := fstest.MapFS{
+		"agents.yaml": {Data: []byte(`agents:
+  - name: Triage Agent
+    model: gpt-5-turbo-ultra
+  - name: Refunds Agent
+    model: gpt-4o-mini
+    tools: [refund_order]
+`)},
+	}
+	tools := option.WithLoadTools(map[string]types.AgentFunction{"refund_order": refundOrder})
+
+	_, err := goswarm.LoadAgents(fsys, "agents.yaml", tools)
+	if err == nil || !strings.Contains(err.Error(), `agents.yaml:3: unknown model "gpt-5-turbo-ultra"`) {
+		t.Fatalf("expected only the SDK models by default, got %v", err)
+	}
+
+	if _, err := goswarm.LoadAgents(fsys, "agents.yaml", tools, option.WithLoadModels("gpt-5-turbo-ultra")); err != nil {
+		t.Fatalf("expected the allowed model besides the SDK models, got %v", err)
+	}
+
+	_, err = goswarm.LoadAgents(fsys, "agents.yaml", option.WithLoadModels("gpt-5-turbo-ultra"),
+		option.WithLoadTools(map[string]types.AgentFunction{"refund_order": nil}))
+	var cerr *types.ConfigError
+	if !errors.As(err, &cerr) || cerr.Line != 6 || !strings.Contains(cerr.Msg, `tool "refund_order" is nil`) {
+		t.Fatalf("expected the nil tool to be reported, got %v", err)
+	}
+}
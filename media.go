@@ -0,0 +1,81 @@
+package goswarm
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// UserMessage creates a user message with the text followed by the parts, e.g. from ImageFile or AudioFile.
+func UserMessage(text string, parts ...openai.ChatCompletionContentPartUnionParam) openai.ChatCompletionUserMessageParam {
+	content := []openai.ChatCompletionContentPartUnionParam{}
+	if text != "" {
+		content = append(content, openai.TextPart(text))
+	}
+	return openai.UserMessageParts(append(content, parts...)...)
+}
+
+// ErrFileParts is returned by AttachFile for files that are neither images nor audio, e.g. PDFs.
+// The version of openai-go this module uses has no file content part.
+var ErrFileParts = errors.New("file content parts are not supported, only images and wav or mp3 audio")
+
+// AttachFile loads an image (png, jpeg, gif, webp) or audio file (wav, mp3) as a content part,
+// depending on the extension of the path.
+func AttachFile(path string) (openai.ChatCompletionContentPartUnionParam, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		return ImageFile(path)
+	case ".wav", ".mp3":
+		return AudioFile(path)
+	}
+	return nil, fmt.Errorf("%s: %w", path, ErrFileParts)
+}
+
+// ImageFile loads an image and embeds it as a data URL.
+func ImageFile(path string) (openai.ChatCompletionContentPartImageParam, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return openai.ChatCompletionContentPartImageParam{}, err
+	}
+	return ImageBytes(data, mime.TypeByExtension(filepath.Ext(path))), nil
+}
+
+// ImageBytes embeds the image as a data URL. The MIME type is detected when it is empty.
+func ImageBytes(data []byte, mimeType string) openai.ChatCompletionContentPartImageParam {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return openai.ImagePart(fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data)))
+}
+
+// AudioFile loads a wav or mp3 file as an input audio part.
+func AudioFile(path string) (openai.ChatCompletionContentPartInputAudioParam, error) {
+	format := openai.ChatCompletionContentPartInputAudioInputAudioFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+	if !format.IsKnown() {
+		return openai.ChatCompletionContentPartInputAudioParam{}, fmt.Errorf("unsupported audio format %q", format)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return openai.ChatCompletionContentPartInputAudioParam{}, err
+	}
+	return AudioBytes(data, format), nil
+}
+
+// AudioBytes creates an input audio part from wav or mp3 data.
+func AudioBytes(data []byte, format openai.ChatCompletionContentPartInputAudioInputAudioFormat) openai.ChatCompletionContentPartInputAudioParam {
+	return openai.ChatCompletionContentPartInputAudioParam{
+		Type: openai.F(openai.ChatCompletionContentPartInputAudioTypeInputAudio),
+		InputAudio: openai.F(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+			Data:   openai.F(base64.StdEncoding.EncodeToString(data)),
+			Format: openai.F(format),
+		}),
+	}
+}
@@ -0,0 +1,117 @@
+package goswarm_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// a 1x1 transparent png
+var pixel = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func takeScreenshot(ctx goswarm.Context) types.Result {
+	if ctx.IsAnalyze() {
+		ctx.SetDescription("Take a screenshot.")
+		return types.Result{}
+	}
+	return types.Result{Value: "Screenshot taken.", Attachments: []openai.ChatCompletionContentPartUnionParam{goswarm.ImageBytes(pixel, "")}}
+}
+
+func TestImageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixel.png")
+	if err := os.WriteFile(path, pixel, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	image, err := goswarm.ImageFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := goswarm.UserMessage("What is this?", image)
+
+	data, err := history.Marshal([]openai.ChatCompletionMessageParamUnion{msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := history.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	media := history.Media(msgs[0])
+	if len(media) != 1 || !strings.HasPrefix(media[0].(openai.ChatCompletionContentPartImageParam).ImageURL.Value.URL.Value, "data:image/png;base64,") {
+		t.Fatalf("expected the image to survive the round trip, got %+v", msgs[0])
+	}
+
+	if _, err := goswarm.AudioFile(filepath.Join(t.TempDir(), "voice.ogg")); err == nil {
+		t.Fatal("expected the unsupported audio format to be rejected")
+	}
+	if _, err := goswarm.AttachFile("invoice.pdf"); !errors.Is(err, goswarm.ErrFileParts) {
+		t.Fatalf("expected file parts to be rejected, got %v", err)
+	}
+	if part, err := goswarm.AttachFile(path); err != nil || len(history.Media(goswarm.UserMessage("", part))) != 1 {
+		t.Fatalf("expected the image to be attached, got %v", err)
+	}
+}
+
+func TestToolAttachments(t *testing.T) {
+	var requests []fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return toolCallReply("call_1", req.Tools[0]["function"].(map[string]any)["name"].(string), "{}")
+		}
+		return assistantReply("A single pixel.")
+	})
+
+	agent := goswarm.NewAgent(option.WithAgentFunctions(takeScreenshot))
+	client.Run(goswarm.NewContext(context.Background()), agent, goswarm.NewMessages(openai.UserMessage("What is on the screen?")))
+
+	// system, user, tool call, tool result, attachments
+	msgs := requests[1].Messages
+	if len(msgs) != 5 || msgs[3]["role"] != "tool" || msgs[4]["role"] != "user" {
+		t.Fatalf("expected the attachments after the tool result, got %+v", msgs)
+	}
+	parts := msgs[4]["content"].([]any)
+	if len(parts) != 2 || parts[1].(map[string]any)["type"] != "image_url" {
+		t.Fatalf("unexpected attachment message: %+v", msgs[4])
+	}
+}
+
+func TestToolAttachmentsKeepTurns(t *testing.T) {
+	var requests []fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return toolCallReply("call_1", req.Tools[0]["function"].(map[string]any)["name"].(string), "{}")
+		}
+		return assistantReply("A single pixel.")
+	})
+
+	// the attachments do not start a turn, the question stays in the last turn
+	agent := goswarm.NewAgent(
+		option.WithAgentFunctions(takeScreenshot),
+		option.WithAgentHistoryStrategy(history.KeepLastTurns(1)),
+	)
+	client.Run(goswarm.NewContext(context.Background()), agent, goswarm.NewMessages(openai.UserMessage("What is on the screen?")))
+
+	// system, user, tool call, tool result, attachments
+	msgs := requests[1].Messages
+	if len(msgs) != 5 || messageText(msgs[1]) != "What is on the screen?" {
+		t.Fatalf("expected the question to be kept with the attachments, got %+v", msgs)
+	}
+	if msgs[4]["name"] != history.AttachmentsName {
+		t.Fatalf("expected the attachments to be marked, got %+v", msgs[4])
+	}
+}
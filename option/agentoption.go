@@ -18,6 +18,15 @@ type AgentOptions struct {
 	Functions         []types.AgentFunction
 	ToolChoice        openai.ChatCompletionToolChoiceOptionUnionParam
 	ParallelToolCalls bool
+	HistoryStrategy   types.HistoryStrategy
+	Summarization     *types.Summarization
+	ApprovalRequired  []types.AgentFunction
+	InputGuardrails   []types.Guardrail
+	OutputGuardrails  []types.Guardrail
+	OutputType        reflect.Type
+	ModelSettings     types.ModelSettings
+	ToolPredicates    []types.ToolPredicate
+	OutputLimits      []types.OutputLimit
 }
 
 var DefAgentOptions = AgentOptions{
@@ -28,12 +37,12 @@ var DefAgentOptions = AgentOptions{
    ParallelToolCalls: true,
 }
 
-// set the model for the agent.
+// set the name for the agent.
 
 type AgentNameOption string
 
 func (o AgentNameOption) ApplyOption(opts *AgentOptions) {
-   opts.Model = string(o)
+   opts.Name = string(o)
 }
 
 func WithAgentName(name string) AgentNameOption {
@@ -114,6 +123,93 @@ func WithAgentFunctions(fn ...types.AgentFunction) AgentFunctionsOption {
 	return fns
 }
 
+// set the agents the agent can call as tools.
+
+type AgentToolsOption struct {
+	tools []*types.AgentTool
+}
+
+func (o AgentToolsOption) ApplyOption(opts *AgentOptions) {
+	for _, tool := range o.tools {
+		opts.Functions = append(opts.Functions, tool)
+	}
+}
+
+func WithAgentTools(tools ...*types.AgentTool) AgentToolsOption {
+	return AgentToolsOption{tools}
+}
+
+// set the agents the agent can hand off to, each gets a transfer_to_<name> tool.
+
+type AgentHandoffsOption struct {
+	handoffs []*types.Handoff
+}
+
+func (o AgentHandoffsOption) ApplyOption(opts *AgentOptions) {
+	for _, handoff := range o.handoffs {
+		opts.Functions = append(opts.Functions, handoff)
+	}
+}
+
+// WithAgentHandoffs takes target agents or handoffs built with goswarm.NewHandoff.
+func WithAgentHandoffs(targets ...any) AgentHandoffsOption {
+	handoffs := make([]*types.Handoff, len(targets))
+	for i, target := range targets {
+		switch v := target.(type) {
+		case *types.Agent:
+			handoffs[i] = &types.Handoff{Agent: v}
+		case *types.Handoff:
+			handoffs[i] = v
+		default:
+			panic("provided value is not an agent or a handoff")
+		}
+	}
+
+	return AgentHandoffsOption{handoffs}
+}
+
+// offer a function to the model only while the predicate, a func(goswarm.Context) bool, returns true.
+
+type AgentToolPredicateOption types.ToolPredicate
+
+func (o AgentToolPredicateOption) ApplyOption(opts *AgentOptions) {
+	opts.ToolPredicates = append(opts.ToolPredicates, types.ToolPredicate(o))
+}
+
+func WithAgentToolPredicate(fn types.AgentFunction, enabled any) AgentToolPredicateOption {
+	if !isContextPredicate(reflect.TypeOf(enabled)) {
+		panic("provided predicate is not a func(goswarm.Context) bool")
+	}
+	return AgentToolPredicateOption{Function: fn, Enabled: enabled}
+}
+
+// isContextPredicate reports whether t is func(goswarm.Context) bool, the option package cannot
+// name the type without an import cycle.
+func isContextPredicate(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Func && t.Name() == "" &&
+		t.NumIn() == 1 && t.In(0).Name() == "Context" && t.In(0).PkgPath() == "github.com/chiwooi/go-swarm" &&
+		t.NumOut() == 1 && t.Out(0).Kind() == reflect.Bool && t.Out(0).Name() == "bool"
+}
+
+// limit the output of a function, or of every function when limit.Function is nil.
+
+type AgentOutputLimitOption types.OutputLimit
+
+func (o AgentOutputLimitOption) ApplyOption(opts *AgentOptions) {
+	opts.OutputLimits = append(opts.OutputLimits, types.OutputLimit(o))
+}
+
+func WithAgentOutputLimit(limit types.OutputLimit) AgentOutputLimitOption {
+	if limit.MaxChars <= 0 {
+		panic("output limit must be positive")
+	}
+	if limit.Strategy == types.SummarizeOutput && limit.Agent == nil {
+		panic("output summaries need an agent")
+	}
+
+	return AgentOutputLimitOption(limit)
+}
+
 // set the parallel tool calls for the agent.
 
 type AgentParallelToolCallsOption bool
@@ -125,3 +221,169 @@ func (o AgentParallelToolCallsOption) ApplyOption(opts *AgentOptions) {
 func WithAgentParallelToolCalls(flag bool) AgentParallelToolCallsOption {
    return AgentParallelToolCallsOption(flag)
 }
+
+// set the history strategy for the agent.
+
+type AgentHistoryStrategyOption struct {
+	strategy types.HistoryStrategy
+}
+
+func (o AgentHistoryStrategyOption) ApplyOption(opts *AgentOptions) {
+   opts.HistoryStrategy = o.strategy
+}
+
+func WithAgentHistoryStrategy(strategy types.HistoryStrategy) AgentHistoryStrategyOption {
+   return AgentHistoryStrategyOption{strategy}
+}
+
+// set the summarization policy for the agent.
+
+type AgentSummarizationOption struct {
+	summarization *types.Summarization
+}
+
+func (o AgentSummarizationOption) ApplyOption(opts *AgentOptions) {
+   opts.Summarization = o.summarization
+}
+
+func WithAgentSummarization(summarization *types.Summarization) AgentSummarizationOption {
+   return AgentSummarizationOption{summarization}
+}
+
+// set the functions whose tool calls require approval.
+
+type AgentApprovalRequiredOption struct {
+	fns []types.AgentFunction
+}
+
+func (o AgentApprovalRequiredOption) ApplyOption(opts *AgentOptions) {
+	opts.ApprovalRequired = append(opts.ApprovalRequired, o.fns...)
+}
+
+func WithAgentApprovalRequired(fn ...types.AgentFunction) AgentApprovalRequiredOption {
+	for _, f := range fn {
+		if reflect.TypeOf(f).Kind() != reflect.Func {
+			panic("provided value is not a function")
+		}
+	}
+
+	return AgentApprovalRequiredOption{fn}
+}
+
+// set the guardrails that check the input of the agent.
+
+type AgentInputGuardrailsOption []types.Guardrail
+
+func (o AgentInputGuardrailsOption) ApplyOption(opts *AgentOptions) {
+	opts.InputGuardrails = append(opts.InputGuardrails, o...)
+}
+
+func WithAgentInputGuardrails(guardrails ...types.Guardrail) AgentInputGuardrailsOption {
+	return AgentInputGuardrailsOption(guardrails)
+}
+
+// set the guardrails that check the output of the agent.
+
+type AgentOutputGuardrailsOption []types.Guardrail
+
+func (o AgentOutputGuardrailsOption) ApplyOption(opts *AgentOptions) {
+	opts.OutputGuardrails = append(opts.OutputGuardrails, o...)
+}
+
+func WithAgentOutputGuardrails(guardrails ...types.Guardrail) AgentOutputGuardrailsOption {
+	return AgentOutputGuardrailsOption(guardrails)
+}
+
+// set the type of the final answer, given as a value of the struct type.
+
+type AgentOutputTypeOption struct {
+	t reflect.Type
+}
+
+func (o AgentOutputTypeOption) ApplyOption(opts *AgentOptions) {
+	opts.OutputType = o.t
+}
+
+func WithAgentOutputType(v any) AgentOutputTypeOption {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("provided value is not a struct")
+	}
+
+	return AgentOutputTypeOption{t}
+}
+
+// set the sampling and request parameters of the agent, the fields that are set replace the current ones.
+
+type AgentModelSettingsOption types.ModelSettings
+
+func (o AgentModelSettingsOption) ApplyOption(opts *AgentOptions) {
+   opts.ModelSettings = opts.ModelSettings.Merge(types.ModelSettings(o))
+}
+
+func WithAgentModelSettings(settings types.ModelSettings) AgentModelSettingsOption {
+   return AgentModelSettingsOption(settings)
+}
+
+// set the sampling temperature of the agent.
+
+func WithAgentTemperature(temperature float64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{Temperature: &temperature}
+}
+
+// set the nucleus sampling probability mass of the agent.
+
+func WithAgentTopP(topP float64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{TopP: &topP}
+}
+
+// set the maximum number of tokens of the answer of the agent.
+
+func WithAgentMaxTokens(maxTokens int64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{MaxTokens: &maxTokens}
+}
+
+// set the seed for deterministic sampling of the agent.
+
+func WithAgentSeed(seed int64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{Seed: &seed}
+}
+
+// set the sequences that end the answer of the agent.
+
+func WithAgentStop(stop ...string) AgentModelSettingsOption {
+   return AgentModelSettingsOption{Stop: stop}
+}
+
+// set the presence penalty of the agent.
+
+func WithAgentPresencePenalty(penalty float64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{PresencePenalty: &penalty}
+}
+
+// set the frequency penalty of the agent.
+
+func WithAgentFrequencyPenalty(penalty float64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{FrequencyPenalty: &penalty}
+}
+
+// set the logit bias of the agent.
+
+func WithAgentLogitBias(bias map[string]int64) AgentModelSettingsOption {
+   return AgentModelSettingsOption{LogitBias: bias}
+}
+
+// set the end-user ID sent with the requests of the agent.
+
+func WithAgentUser(user string) AgentModelSettingsOption {
+   return AgentModelSettingsOption{User: user}
+}
+
+// set the metadata sent with the requests of the agent.
+
+func WithAgentMetadata(metadata map[string]string) AgentModelSettingsOption {
+   return AgentModelSettingsOption{Metadata: metadata}
+}
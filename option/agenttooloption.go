@@ -0,0 +1,94 @@
+package option
+
+import (
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type AgentToolOption interface {
+	ApplyOption(opts *AgentToolOptions)
+}
+
+type AgentToolOptions struct {
+	Name        string
+	Description string
+	SeedHistory bool
+	MaxTurns    int
+	ShareBudget bool
+	Variables   types.VariableScope
+}
+
+var DefAgentToolOptions = AgentToolOptions{
+	Variables: types.ShareVariables,
+}
+
+// set the name the model calls the tool by.
+
+type ToolNameOption string
+
+func (o ToolNameOption) ApplyOption(opts *AgentToolOptions) {
+	opts.Name = string(o)
+}
+
+func WithToolName(name string) ToolNameOption {
+	return ToolNameOption(name)
+}
+
+// set the description of the tool.
+
+type ToolDescriptionOption string
+
+func (o ToolDescriptionOption) ApplyOption(opts *AgentToolOptions) {
+	opts.Description = string(o)
+}
+
+func WithToolDescription(desc string) ToolDescriptionOption {
+	return ToolDescriptionOption(desc)
+}
+
+// start the nested run with the history of the caller.
+
+type ToolSeedHistoryOption bool
+
+func (o ToolSeedHistoryOption) ApplyOption(opts *AgentToolOptions) {
+	opts.SeedHistory = bool(o)
+}
+
+func WithToolSeedHistory(flag bool) ToolSeedHistoryOption {
+	return ToolSeedHistoryOption(flag)
+}
+
+// limit the turns of the nested run.
+
+type ToolMaxTurnsOption int
+
+func (o ToolMaxTurnsOption) ApplyOption(opts *AgentToolOptions) {
+	opts.MaxTurns = int(o)
+}
+
+func WithToolMaxTurns(maxTurns int) ToolMaxTurnsOption {
+	return ToolMaxTurnsOption(maxTurns)
+}
+
+// give the nested run at most the turns the caller has left.
+
+type ToolShareBudgetOption bool
+
+func (o ToolShareBudgetOption) ApplyOption(opts *AgentToolOptions) {
+	opts.ShareBudget = bool(o)
+}
+
+func WithToolShareBudget(flag bool) ToolShareBudgetOption {
+	return ToolShareBudgetOption(flag)
+}
+
+// set how the nested run sees the context variables of the caller.
+
+type ToolVariablesOption types.VariableScope
+
+func (o ToolVariablesOption) ApplyOption(opts *AgentToolOptions) {
+	opts.Variables = types.VariableScope(o)
+}
+
+func WithToolVariables(scope types.VariableScope) ToolVariablesOption {
+	return ToolVariablesOption(scope)
+}
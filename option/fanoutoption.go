@@ -0,0 +1,101 @@
+package option
+
+import (
+	"time"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type FanOutOption interface {
+	ApplyOption(opts *FanOutOptions)
+}
+
+type FanOutOptions struct {
+	// Limits each branch, zero means no limit.
+	Timeout time.Duration
+	Policy  types.FailurePolicy
+	Quorum  int
+	// Combines the answers, the reducer takes precedence over the aggregator agent.
+	Aggregator *types.Agent
+	Reducer    types.Reducer
+	RunOptions []RunOption
+}
+
+var DefFanOutOptions = FanOutOptions{
+	Policy: types.FailFast,
+}
+
+// set the timeout of each branch.
+
+type BranchTimeoutOption time.Duration
+
+func (o BranchTimeoutOption) ApplyOption(opts *FanOutOptions) {
+	opts.Timeout = time.Duration(o)
+}
+
+func WithBranchTimeout(timeout time.Duration) BranchTimeoutOption {
+	return BranchTimeoutOption(timeout)
+}
+
+// set how failed branches are handled.
+
+type FailurePolicyOption types.FailurePolicy
+
+func (o FailurePolicyOption) ApplyOption(opts *FanOutOptions) {
+	opts.Policy = types.FailurePolicy(o)
+}
+
+func WithFailurePolicy(policy types.FailurePolicy) FailurePolicyOption {
+	return FailurePolicyOption(policy)
+}
+
+// require at least n successful branches.
+
+type QuorumOption int
+
+func (o QuorumOption) ApplyOption(opts *FanOutOptions) {
+	opts.Policy = types.Quorum
+	opts.Quorum = int(o)
+}
+
+func WithQuorum(n int) QuorumOption {
+	return QuorumOption(n)
+}
+
+// set the agent that combines the answers of the branches.
+
+type AggregatorOption struct {
+	agent *types.Agent
+}
+
+func (o AggregatorOption) ApplyOption(opts *FanOutOptions) {
+	opts.Aggregator = o.agent
+}
+
+func WithAggregator(agent *types.Agent) AggregatorOption {
+	return AggregatorOption{agent}
+}
+
+// set the function that combines the answers of the branches.
+
+type ReducerOption types.Reducer
+
+func (o ReducerOption) ApplyOption(opts *FanOutOptions) {
+	opts.Reducer = types.Reducer(o)
+}
+
+func WithReducer(reducer types.Reducer) ReducerOption {
+	return ReducerOption(reducer)
+}
+
+// set the run options of the branches and the aggregator.
+
+type BranchRunOptionsOption []RunOption
+
+func (o BranchRunOptionsOption) ApplyOption(opts *FanOutOptions) {
+	opts.RunOptions = append(opts.RunOptions, o...)
+}
+
+func WithBranchRunOptions(opts ...RunOption) BranchRunOptionsOption {
+	return BranchRunOptionsOption(opts)
+}
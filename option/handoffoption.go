@@ -0,0 +1,95 @@
+package option
+
+import (
+	"reflect"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type HandoffOption interface {
+	ApplyOption(opts *HandoffOptions)
+}
+
+type HandoffOptions struct {
+	Name         string
+	Description  string
+	SummaryAgent *types.Agent
+	InputFilter  types.HistoryStrategy
+	Payload      reflect.Type
+}
+
+var DefHandoffOptions = HandoffOptions{}
+
+// set the name the model calls the transfer tool by.
+
+type HandoffNameOption string
+
+func (o HandoffNameOption) ApplyOption(opts *HandoffOptions) {
+	opts.Name = string(o)
+}
+
+func WithHandoffName(name string) HandoffNameOption {
+	return HandoffNameOption(name)
+}
+
+// set the description of the transfer tool.
+
+type HandoffDescriptionOption string
+
+func (o HandoffDescriptionOption) ApplyOption(opts *HandoffOptions) {
+	opts.Description = string(o)
+}
+
+func WithHandoffDescription(desc string) HandoffDescriptionOption {
+	return HandoffDescriptionOption(desc)
+}
+
+// set the agent that summarises the history for the receiving agent.
+
+type HandoffSummaryOption struct {
+	agent *types.Agent
+}
+
+func (o HandoffSummaryOption) ApplyOption(opts *HandoffOptions) {
+	opts.SummaryAgent = o.agent
+}
+
+func WithHandoffSummary(agent *types.Agent) HandoffSummaryOption {
+	return HandoffSummaryOption{agent}
+}
+
+// set the filter of the history the receiving agent sees, e.g. history.StripToolCalls().
+
+type HandoffInputFilterOption struct {
+	filter types.HistoryStrategy
+}
+
+func (o HandoffInputFilterOption) ApplyOption(opts *HandoffOptions) {
+	opts.InputFilter = o.filter
+}
+
+func WithHandoffInputFilter(filter types.HistoryStrategy) HandoffInputFilterOption {
+	return HandoffInputFilterOption{filter}
+}
+
+// set the payload the model passes along with the handoff, given as a value of the struct type.
+
+type HandoffPayloadOption struct {
+	t reflect.Type
+}
+
+func (o HandoffPayloadOption) ApplyOption(opts *HandoffOptions) {
+	opts.Payload = o.t
+}
+
+func WithHandoffPayload(v any) HandoffPayloadOption {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("provided value is not a struct")
+	}
+
+	return HandoffPayloadOption{t}
+}
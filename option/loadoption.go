@@ -0,0 +1,58 @@
+package option
+
+import (
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type LoadOption interface {
+	ApplyOption(opts *LoadOptions)
+}
+
+type LoadOptions struct {
+	Tools     map[string]types.AgentFunction
+	Models    []string
+	Templates []TemplateOption
+}
+
+var DefLoadOptions = LoadOptions{}
+
+// add the functions the agent definitions can refer to by name.
+
+type LoadToolsOption map[string]types.AgentFunction
+
+func (o LoadToolsOption) ApplyOption(opts *LoadOptions) {
+	if opts.Tools == nil {
+		opts.Tools = map[string]types.AgentFunction{}
+	}
+	for name, fn := range o {
+		opts.Tools[name] = fn
+	}
+}
+
+func WithLoadTools(tools map[string]types.AgentFunction) LoadToolsOption {
+	return LoadToolsOption(tools)
+}
+
+// set the models the agent definitions may use besides the chat models of the OpenAI SDK, e.g. fine-tuned ones.
+
+type LoadModelsOption []string
+
+func (o LoadModelsOption) ApplyOption(opts *LoadOptions) {
+	opts.Models = append(opts.Models, o...)
+}
+
+func WithLoadModels(models ...string) LoadModelsOption {
+	return LoadModelsOption(models)
+}
+
+// set the partials, functions and strictness of the instruction templates.
+
+type LoadTemplateOption []TemplateOption
+
+func (o LoadTemplateOption) ApplyOption(opts *LoadOptions) {
+	opts.Templates = append(opts.Templates, o...)
+}
+
+func WithLoadTemplateOptions(opts ...TemplateOption) LoadTemplateOption {
+	return LoadTemplateOption(opts)
+}
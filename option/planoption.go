@@ -0,0 +1,56 @@
+package option
+
+import (
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type PlanOption interface {
+	ApplyOption(opts *PlanOptions)
+}
+
+type PlanOptions struct {
+	// Agents the planner can assign tasks to, in addition to the registry of the Swarm.
+	Agents     []*types.Agent
+	MaxReplans int
+	RunOptions []RunOption
+}
+
+var DefPlanOptions = PlanOptions{
+	MaxReplans: 1,
+}
+
+// set the agents the planner can assign tasks to.
+
+type PlanAgentsOption []*types.Agent
+
+func (o PlanAgentsOption) ApplyOption(opts *PlanOptions) {
+	opts.Agents = append(opts.Agents, o...)
+}
+
+func WithPlanAgents(agents ...*types.Agent) PlanAgentsOption {
+	return PlanAgentsOption(agents)
+}
+
+// set the number of times the plan is revised after a failed task.
+
+type MaxReplansOption int
+
+func (o MaxReplansOption) ApplyOption(opts *PlanOptions) {
+	opts.MaxReplans = int(o)
+}
+
+func WithMaxReplans(n int) MaxReplansOption {
+	return MaxReplansOption(n)
+}
+
+// set the run options of the planner and the tasks.
+
+type PlanRunOptionsOption []RunOption
+
+func (o PlanRunOptionsOption) ApplyOption(opts *PlanOptions) {
+	opts.RunOptions = append(opts.RunOptions, o...)
+}
+
+func WithPlanRunOptions(opts ...RunOption) PlanRunOptionsOption {
+	return PlanRunOptionsOption(opts)
+}
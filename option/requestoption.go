@@ -1,5 +1,9 @@
 package option
 
+import (
+	"github.com/chiwooi/go-swarm/types"
+)
+
 type RunOption interface {
    ApplyOption(opts *RunOptions)
 }
@@ -10,12 +14,31 @@ type RunOptions struct {
 	Debug         bool
 	MaxTurns      int
 	ExecuteTools  bool
+	// Overrides the history strategy of the agent.
+	HistoryStrategy types.HistoryStrategy
+	// Overrides the summarization policy of the agent.
+	Summarization *types.Summarization
+	// Running summary of a previous run on the same history.
+	HistorySummary types.HistorySummary
+	// Checkpoints the run under this ID after every step, requires a checkpoint store on the Swarm.
+	CheckpointID string
+	// Saves the artifacts of the run in this scope, e.g. the one of a previous run on the same history.
+	ArtifactScope string
+	// Decisions for the tool calls a previous run paused on, set by Continue and Resume.
+	Approvals []types.ApprovalDecision
+	// Number of times RunTyped asks the model to fix an answer that does not decode.
+	MaxRepairs int
+	// Limits the handoffs of the run.
+	HandoffPolicy *types.HandoffPolicy
+	// Overrides the sampling and request parameters of the agents.
+	ModelSettings types.ModelSettings
 }
 
 var DefRunOptions = RunOptions{
    Model:        "gpt-4o",
    MaxTurns:     9999,
    ExecuteTools: true,
+   MaxRepairs:   2,
    Stream:       false,
    Debug:        false,
 }
@@ -73,3 +96,163 @@ func (o ExecuteToolsOption) ApplyOption(opts *RunOptions) {
 func WithExecuteTools(exec bool) ExecuteToolsOption {
    return ExecuteToolsOption(exec)
 }
+
+
+type HistoryStrategyOption struct {
+	strategy types.HistoryStrategy
+}
+
+func (o HistoryStrategyOption) ApplyOption(opts *RunOptions) {
+   opts.HistoryStrategy = o.strategy
+}
+
+func WithHistoryStrategy(strategy types.HistoryStrategy) HistoryStrategyOption {
+   return HistoryStrategyOption{strategy}
+}
+
+
+type SummarizationOption struct {
+	summarization *types.Summarization
+}
+
+func (o SummarizationOption) ApplyOption(opts *RunOptions) {
+   opts.Summarization = o.summarization
+}
+
+func WithSummarization(summarization *types.Summarization) SummarizationOption {
+   return SummarizationOption{summarization}
+}
+
+
+// set the running summary a previous run on the same history returned in Response.Summary.
+
+type HistorySummaryOption types.HistorySummary
+
+func (o HistorySummaryOption) ApplyOption(opts *RunOptions) {
+   opts.HistorySummary = types.HistorySummary(o)
+}
+
+func WithHistorySummary(summary types.HistorySummary) HistorySummaryOption {
+   return HistorySummaryOption(summary)
+}
+
+
+type CheckpointIDOption string
+
+func (o CheckpointIDOption) ApplyOption(opts *RunOptions) {
+   opts.CheckpointID = string(o)
+}
+
+func WithCheckpointID(id string) CheckpointIDOption {
+   return CheckpointIDOption(id)
+}
+
+
+// set the scope the run saves its artifacts in, e.g. Response.ArtifactScope of a previous run on
+// the same history so that its artifacts can still be read.
+
+type ArtifactScopeOption string
+
+func (o ArtifactScopeOption) ApplyOption(opts *RunOptions) {
+   opts.ArtifactScope = string(o)
+}
+
+func WithArtifactScope(scope string) ArtifactScopeOption {
+   return ArtifactScopeOption(scope)
+}
+
+
+type MaxRepairsOption int
+
+func (o MaxRepairsOption) ApplyOption(opts *RunOptions) {
+   opts.MaxRepairs = int(o)
+}
+
+func WithMaxRepairs(n int) MaxRepairsOption {
+   return MaxRepairsOption(n)
+}
+
+
+type HandoffPolicyOption struct {
+	policy *types.HandoffPolicy
+}
+
+func (o HandoffPolicyOption) ApplyOption(opts *RunOptions) {
+   opts.HandoffPolicy = o.policy
+}
+
+func WithHandoffPolicy(policy types.HandoffPolicy) HandoffPolicyOption {
+   return HandoffPolicyOption{&policy}
+}
+
+// set the sampling and request parameters of the run, the fields that are set replace the current ones.
+
+type ModelSettingsOption types.ModelSettings
+
+func (o ModelSettingsOption) ApplyOption(opts *RunOptions) {
+   opts.ModelSettings = opts.ModelSettings.Merge(types.ModelSettings(o))
+}
+
+func WithModelSettings(settings types.ModelSettings) ModelSettingsOption {
+   return ModelSettingsOption(settings)
+}
+
+// set the sampling temperature of the run.
+
+func WithTemperature(temperature float64) ModelSettingsOption {
+   return ModelSettingsOption{Temperature: &temperature}
+}
+
+// set the nucleus sampling probability mass of the run.
+
+func WithTopP(topP float64) ModelSettingsOption {
+   return ModelSettingsOption{TopP: &topP}
+}
+
+// set the maximum number of tokens of the answer of the run.
+
+func WithMaxTokens(maxTokens int64) ModelSettingsOption {
+   return ModelSettingsOption{MaxTokens: &maxTokens}
+}
+
+// set the seed for deterministic sampling of the run.
+
+func WithSeed(seed int64) ModelSettingsOption {
+   return ModelSettingsOption{Seed: &seed}
+}
+
+// set the sequences that end the answer of the run.
+
+func WithStop(stop ...string) ModelSettingsOption {
+   return ModelSettingsOption{Stop: stop}
+}
+
+// set the presence penalty of the run.
+
+func WithPresencePenalty(penalty float64) ModelSettingsOption {
+   return ModelSettingsOption{PresencePenalty: &penalty}
+}
+
+// set the frequency penalty of the run.
+
+func WithFrequencyPenalty(penalty float64) ModelSettingsOption {
+   return ModelSettingsOption{FrequencyPenalty: &penalty}
+}
+
+// set the logit bias of the run.
+
+func WithLogitBias(bias map[string]int64) ModelSettingsOption {
+   return ModelSettingsOption{LogitBias: bias}
+}
+
+// set the end-user ID sent with the requests of the run.
+
+func WithUser(user string) ModelSettingsOption {
+   return ModelSettingsOption{User: user}
+}
+
+// set the metadata sent with the requests of the run.
+
+func WithMetadata(metadata map[string]string) ModelSettingsOption {
+   return ModelSettingsOption{Metadata: metadata}
+}
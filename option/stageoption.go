@@ -0,0 +1,55 @@
+package option
+
+type StageOption interface {
+	ApplyOption(opts *StageOptions)
+}
+
+type StageOptions struct {
+	Input      any
+	When       any
+	MaxRetries int
+}
+
+var DefStageOptions = StageOptions{}
+
+// set the function that builds the input of the stage.
+
+type StageInputOption struct {
+	input any
+}
+
+func (o StageInputOption) ApplyOption(opts *StageOptions) {
+	opts.Input = o.input
+}
+
+// WithStageInput takes a func(goswarm.Context, *types.PipelineResult) string.
+func WithStageInput(input any) StageInputOption {
+	return StageInputOption{input}
+}
+
+// set the condition of the stage.
+
+type StageWhenOption struct {
+	when any
+}
+
+func (o StageWhenOption) ApplyOption(opts *StageOptions) {
+	opts.When = o.when
+}
+
+// WithStageWhen takes a func(goswarm.Context, *types.PipelineResult) bool.
+func WithStageWhen(when any) StageWhenOption {
+	return StageWhenOption{when}
+}
+
+// set the number of retries of the stage.
+
+type StageRetriesOption int
+
+func (o StageRetriesOption) ApplyOption(opts *StageOptions) {
+	opts.MaxRetries = int(o)
+}
+
+func WithStageRetries(n int) StageRetriesOption {
+	return StageRetriesOption(n)
+}
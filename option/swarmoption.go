@@ -0,0 +1,112 @@
+package option
+
+import (
+	"github.com/chiwooi/go-swarm/artifact"
+	"github.com/chiwooi/go-swarm/checkpoint"
+	"github.com/chiwooi/go-swarm/redact"
+	"github.com/chiwooi/go-swarm/session"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type SwarmOption interface {
+	ApplyOption(opts *SwarmOptions)
+}
+
+type SwarmOptions struct {
+	SessionStore    session.Store
+	Registry        *types.Registry
+	StartAgent      *types.Agent
+	CheckpointStore checkpoint.Store
+	Redaction       []redact.Detector
+	ArtifactStore   artifact.Store
+}
+
+var DefSwarmOptions = SwarmOptions{}
+
+// set the store used by RunSession.
+
+type SessionStoreOption struct {
+	store session.Store
+}
+
+func (o SessionStoreOption) ApplyOption(opts *SwarmOptions) {
+	opts.SessionStore = o.store
+}
+
+func WithSessionStore(store session.Store) SessionStoreOption {
+	return SessionStoreOption{store}
+}
+
+// set the registry used to resolve agents by name.
+
+type RegistryOption struct {
+	registry *types.Registry
+}
+
+func (o RegistryOption) ApplyOption(opts *SwarmOptions) {
+	opts.Registry = o.registry
+}
+
+func WithRegistry(registry *types.Registry) RegistryOption {
+	return RegistryOption{registry}
+}
+
+// set the agent that handles new sessions.
+
+type StartAgentOption struct {
+	agent *types.Agent
+}
+
+func (o StartAgentOption) ApplyOption(opts *SwarmOptions) {
+	opts.StartAgent = o.agent
+}
+
+func WithStartAgent(agent *types.Agent) StartAgentOption {
+	return StartAgentOption{agent}
+}
+
+// set the store used to checkpoint runs.
+
+type CheckpointStoreOption struct {
+	store checkpoint.Store
+}
+
+func (o CheckpointStoreOption) ApplyOption(opts *SwarmOptions) {
+	opts.CheckpointStore = o.store
+}
+
+func WithCheckpointStore(store checkpoint.Store) CheckpointStoreOption {
+	return CheckpointStoreOption{store}
+}
+
+// set the detectors of the personal data that is redacted before it is sent to the model.
+
+type RedactionOption struct {
+	detectors []redact.Detector
+}
+
+func (o RedactionOption) ApplyOption(opts *SwarmOptions) {
+	opts.Redaction = o.detectors
+}
+
+// WithRedaction enables redaction with the given detectors, or with redact.Defaults() when none are given.
+func WithRedaction(detectors ...redact.Detector) RedactionOption {
+	if len(detectors) == 0 {
+		detectors = redact.Defaults()
+	}
+	return RedactionOption{detectors}
+}
+
+// set the store of the tool outputs limited with types.ArtifactOutput.
+
+type ArtifactStoreOption struct {
+	store artifact.Store
+}
+
+func (o ArtifactStoreOption) ApplyOption(opts *SwarmOptions) {
+	opts.ArtifactStore = o.store
+}
+
+func WithArtifactStore(store artifact.Store) ArtifactStoreOption {
+	return ArtifactStoreOption{store}
+}
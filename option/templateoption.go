@@ -0,0 +1,60 @@
+package option
+
+import (
+	"text/template"
+)
+
+type TemplateOption interface {
+	ApplyOption(opts *TemplateOptions)
+}
+
+type TemplateOptions struct {
+	Partials *template.Template
+	Funcs    template.FuncMap
+	Strict   bool
+}
+
+var DefTemplateOptions = TemplateOptions{}
+
+// set the templates the instructions can include with {{template "name" .}}, shared across agents.
+
+type TemplatePartialsOption struct {
+	partials *template.Template
+}
+
+func (o TemplatePartialsOption) ApplyOption(opts *TemplateOptions) {
+	opts.Partials = o.partials
+}
+
+func WithTemplatePartials(partials *template.Template) TemplatePartialsOption {
+	return TemplatePartialsOption{partials}
+}
+
+// add functions the instructions can call.
+
+type TemplateFuncsOption template.FuncMap
+
+func (o TemplateFuncsOption) ApplyOption(opts *TemplateOptions) {
+	if opts.Funcs == nil {
+		opts.Funcs = template.FuncMap{}
+	}
+	for name, f := range o {
+		opts.Funcs[name] = f
+	}
+}
+
+func WithTemplateFuncs(funcs template.FuncMap) TemplateFuncsOption {
+	return TemplateFuncsOption(funcs)
+}
+
+// fail on missing context variables instead of rendering "<no value>".
+
+type TemplateStrictOption bool
+
+func (o TemplateStrictOption) ApplyOption(opts *TemplateOptions) {
+	opts.Strict = bool(o)
+}
+
+func WithTemplateStrict(flag bool) TemplateStrictOption {
+	return TemplateStrictOption(flag)
+}
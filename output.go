@@ -0,0 +1,126 @@
+package goswarm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+const repairPrompt = `Your answer could not be decoded: %v
+Answer again with only a JSON object that matches the requested schema.`
+
+var invalidSchemaName = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// outputFormat requests a JSON answer that follows the schema of the struct type, fields are named by their json tags.
+func outputFormat(t reflect.Type) openai.ResponseFormatJSONSchemaParam {
+	name := invalidSchemaName.ReplaceAllString(t.Name(), "_")
+	if name == "" {
+		name = "output"
+	}
+
+	return openai.ResponseFormatJSONSchemaParam{
+		Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+		JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+			Name:   openai.F(name),
+			Schema: openai.F[any](objectSchema(t, true, nil)),
+			Strict: openai.F(true),
+		}),
+	}
+}
+
+// RunTyped runs the agent with T as its output type and decodes the final answer into T.
+// When the answer does not decode, the error is sent back to the model and the run continues,
+// at most option.WithMaxRepairs times. The returned response holds the messages of every attempt.
+func RunTyped[T any](s *Swarm, ctx Context, agent *types.Agent, messages []openai.ChatCompletionMessageParamUnion, opts ...option.RunOption) (T, *types.Response, error) {
+	var out T
+
+	args := option.DefRunOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+
+	// reflect.TypeOf(out) is nil for interface types
+	outputType := reflect.TypeOf((*T)(nil)).Elem()
+	if outputType.Kind() != reflect.Struct {
+		return out, nil, fmt.Errorf("output type %v is not a struct", outputType)
+	}
+	agent = withOutputType(agent, outputType)
+
+	result := &types.Response{Agent: agent}
+	conversation := messages
+
+	for repairs := 0; ; repairs++ {
+		resp := s.Run(ctx, agent, conversation, opts...)
+		result.Messages = append(result.Messages, resp.Messages...)
+		result.Agent = resp.Agent
+		result.PendingApprovals = resp.PendingApprovals
+		result.Error = resp.Error
+		result.Handoffs = append(result.Handoffs, resp.Handoffs...)
+
+		if resp.Error != nil {
+			return out, result, resp.Error
+		}
+		if len(resp.PendingApprovals) > 0 {
+			return out, result, errors.New("run paused for approval before the final answer")
+		}
+
+		err := decodeOutput(resp.Messages, &out)
+		if err == nil {
+			return out, result, nil
+		}
+		if repairs >= args.MaxRepairs {
+			return out, result, err
+		}
+
+		debugPrint(args.Debug, "Repairing the answer: %v", err)
+		repair := openai.UserMessage(fmt.Sprintf(repairPrompt, err))
+		result.Messages = append(result.Messages, repair)
+
+		conversation = append(append(conversation[:len(conversation):len(conversation)], resp.Messages...), repair)
+		agent = withOutputType(resp.Agent, outputType)
+	}
+}
+
+// withOutputType returns the agent, or a copy of it when it declares another output type.
+func withOutputType(agent *types.Agent, t reflect.Type) *types.Agent {
+	if agent.OutputType == t {
+		return agent
+	}
+
+	typed := *agent
+	typed.OutputType = t
+	return &typed
+}
+
+// trimCodeFence removes the markdown code fence models like to put around JSON.
+func trimCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	return strings.Trim(text, "`\n ")
+}
+
+// decodeOutput decodes the content of the last assistant message.
+func decodeOutput(msgs []openai.ChatCompletionMessageParamUnion, out any) error {
+	if len(msgs) == 0 {
+		return errors.New("the run returned no answer")
+	}
+
+	last := msgs[len(msgs)-1]
+	if history.Role(last) != "assistant" || len(history.ToolCalls(last)) > 0 {
+		return errors.New("the run ended without a final answer")
+	}
+
+	if err := json.Unmarshal([]byte(trimCodeFence(history.Text(last))), out); err != nil {
+		return fmt.Errorf("decode %T: %w", out, err)
+	}
+	return nil
+}
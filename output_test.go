@@ -0,0 +1,159 @@
+package goswarm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+)
+
+type verdict struct {
+	Value  bool   `json:"value" desc:"whether the data passes" required:"true"`
+	Reason string `json:"reason" desc:"the reason for the verdict"`
+}
+
+func TestRunTyped(t *testing.T) {
+	var requests []fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return assistantReply("Sure! The value is true.")
+		}
+		return assistantReply("```json\n{\"value\": true, \"reason\": \"looks fine\"}\n```")
+	})
+
+	agent := goswarm.NewAgent(option.WithAgentInstructions("Evaluate the data."))
+	ctx := goswarm.NewContext(context.Background())
+
+	out, resp, err := goswarm.RunTyped[verdict](client, ctx, agent, goswarm.NewMessages(openai.UserMessage("data")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Value || out.Reason != "looks fine" {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+
+	format := requests[0].ResponseFormat
+	schema, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || schema["name"] != "verdict" {
+		t.Fatalf("expected a json_schema response format, got %+v", format)
+	}
+	properties := schema["schema"].(map[string]any)["properties"].(map[string]any)
+	if properties["value"].(map[string]any)["type"] != "boolean" {
+		t.Fatalf("unexpected schema properties: %+v", properties)
+	}
+	if got := fmt.Sprint(schema["schema"].(map[string]any)["required"]); got != "[value reason]" {
+		t.Fatalf("expected every field to be required, got %s", got)
+	}
+	if schema["schema"].(map[string]any)["additionalProperties"] != false || schema["strict"] != true {
+		t.Fatalf("expected a strict schema without additional properties, got %+v", schema)
+	}
+
+	// invalid answer, repair prompt and the repaired answer
+	if len(requests) != 2 || len(resp.Messages) != 3 {
+		t.Fatalf("expected one repair, got %d requests and %d messages", len(requests), len(resp.Messages))
+	}
+
+	// the repair loop is bounded
+	requests = nil
+	client = newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		return assistantReply("no JSON here")
+	})
+	if _, _, err := goswarm.RunTyped[verdict](client, ctx, agent, goswarm.NewMessages(openai.UserMessage("data")), option.WithMaxRepairs(1)); err == nil {
+		t.Fatal("expected a decoding error")
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(requests))
+	}
+}
+
+type lineItem struct {
+	SKU      string `json:"sku" desc:"article number"`
+	Quantity int    `json:"quantity"`
+}
+
+type order struct {
+	Customer struct {
+		Name  string `json:"name"`
+		Email string `json:"email,omitempty"`
+	} `json:"customer"`
+	Items []lineItem `json:"items" desc:"ordered articles"`
+	Tags  []string   `json:"tags,omitempty"`
+	Note  string     `json:"-"`
+}
+
+type orderArgs struct {
+	Items []lineItem `desc:"ordered articles" required:"true"`
+	Tags  []string   `desc:"labels of the order"`
+}
+
+var ordered []lineItem
+
+func PlaceOrder(ctx goswarm.Context, args orderArgs) string {
+	if !ctx.IsAnalyze() {
+		ordered = append(ordered, args.Items...)
+	}
+	return fmt.Sprintf("%d items tagged %v", len(args.Items), args.Tags)
+}
+
+func TestRunTypedNotStruct(t *testing.T) {
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		t.Fatal("expected no model call")
+		return nil
+	})
+	ctx := goswarm.NewContext(context.Background())
+
+	if _, _, err := goswarm.RunTyped[fmt.Stringer](client, ctx, goswarm.NewAgent(), goswarm.NewMessages(openai.UserMessage("data"))); err == nil {
+		t.Fatal("expected an interface output type to be rejected")
+	}
+	if _, _, err := goswarm.RunTyped[string](client, ctx, goswarm.NewAgent(), goswarm.NewMessages(openai.UserMessage("data"))); err == nil {
+		t.Fatal("expected a string output type to be rejected")
+	}
+}
+
+func TestNestedSchema(t *testing.T) {
+	var requests []fakeRequest
+	ordered = nil
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return toolCallReply("call_1", req.Tools[0]["function"].(map[string]any)["name"].(string),
+				`{"Items":[{"sku":"A-1","quantity":2}],"Tags":["gift"]}`)
+		}
+		return assistantReply(`{"customer":{"name":"Jane"},"items":[{"sku":"A-1","quantity":2}]}`)
+	})
+
+	agent := goswarm.NewAgent(option.WithAgentFunctions(PlaceOrder))
+	ctx := goswarm.NewContext(context.Background())
+
+	out, _, err := goswarm.RunTyped[order](client, ctx, agent, goswarm.NewMessages(openai.UserMessage("Two of A-1.")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Customer.Name != "Jane" || len(out.Items) != 1 || out.Items[0].Quantity != 2 {
+		t.Fatalf("unexpected output: %+v", out)
+	}
+	if len(ordered) != 1 || ordered[0] != (lineItem{SKU: "A-1", Quantity: 2}) {
+		t.Fatalf("expected the tool to get the decoded items, got %+v", ordered)
+	}
+
+	tool, _ := json.Marshal(requests[0].Tools[0]["function"].(map[string]any)["parameters"])
+	want := `{"properties":{"Items":{"description":"ordered articles","items":{"properties":{"quantity":{"description":"","type":"integer"},"sku":{"description":"article number","type":"string"}},"required":[],"type":"object"},"type":"array"},"Tags":{"description":"labels of the order","items":{"type":"string"},"type":"array"}},"required":["Items"],"type":"object"}`
+	if string(tool) != want {
+		t.Fatalf("unexpected tool schema:\n got %s\nwant %s", tool, want)
+	}
+
+	format, _ := json.Marshal(requests[0].ResponseFormat["json_schema"].(map[string]any)["schema"])
+	want = `{"additionalProperties":false,"properties":{"customer":{"additionalProperties":false,"description":"","properties":{"email":{"description":"","type":["string","null"]},"name":{"description":"","type":"string"}},"required":["name","email"],"type":"object"},"items":{"description":"ordered articles","items":{"additionalProperties":false,"properties":{"quantity":{"description":"","type":"integer"},"sku":{"description":"article number","type":"string"}},"required":["sku","quantity"],"type":"object"},"type":"array"},"tags":{"description":"","items":{"type":"string"},"type":["array","null"]}},"required":["customer","items","tags"],"type":"object"}`
+	if string(format) != want {
+		t.Fatalf("unexpected response format:\n got %s\nwant %s", format, want)
+	}
+}
@@ -0,0 +1,247 @@
+package goswarm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/artifact"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+const readArtifactName = "read_artifact"
+
+// artifactPageSize is the default and the largest number of characters read_artifact returns.
+const artifactPageSize = 4000
+
+const outputSummaryPrompt = `Summarize the output of the tool %s below for the assistant that called it.
+Keep every identifier, number and error message it will need, drop the rest.
+
+%s`
+
+// artifactReader is the read_artifact tool, offered to agents with a types.ArtifactOutput limit.
+type artifactReader struct{}
+
+var readArtifact = &artifactReader{}
+
+// artifactScope returns the scope the artifacts of a run are saved in: the one of the run options,
+// the one of the run that started a nested run, or a new one. The artifacts outlive the run, the
+// history it returns still refers to them.
+func artifactScope(ctx Context, args option.RunOptions) string {
+	if args.ArtifactScope != "" {
+		return args.ArtifactScope
+	}
+	if parent := getRunScope(ctx); parent != nil {
+		return parent.state.artifactScope
+	}
+	if args.CheckpointID != "" {
+		// the same scope after Resume
+		return "run/" + args.CheckpointID
+	}
+
+	var id [8]byte
+	rand.Read(id[:])
+	return "run/" + hex.EncodeToString(id[:])
+}
+
+// runArtifactScope returns the artifact scope of the run of ctx.
+func runArtifactScope(ctx Context) string {
+	if scope := getRunScope(ctx); scope != nil {
+		return scope.state.artifactScope
+	}
+	return ""
+}
+
+// DeleteArtifacts removes the tool outputs saved in the artifact scope of a run, see Response.ArtifactScope.
+// Runs keep their artifacts, so that later runs on the same history can still read them.
+func (s *Swarm) DeleteArtifacts(ctx Context, scope string) error {
+	if s.options.ArtifactStore == nil {
+		return errors.New("no artifact store configured, use option.WithArtifactStore")
+	}
+	return s.options.ArtifactStore.DeleteScope(ctx, scope)
+}
+
+// savesArtifacts tells whether one of the output limits of the agent saves artifacts to the store
+// of the Swarm. Without a store the outputs are truncated and read_artifact is not offered.
+func (s *Swarm) savesArtifacts(agent *types.Agent) bool {
+	if s.options.ArtifactStore == nil {
+		return false
+	}
+	for _, limit := range agent.OutputLimits {
+		if limit.Strategy == types.ArtifactOutput {
+			return true
+		}
+	}
+	return false
+}
+
+func artifactReaderToJSON() openai.ChatCompletionToolParam {
+	return openai.ChatCompletionToolParam{
+		Type: openai.F(openai.ChatCompletionToolTypeFunction),
+		Function: openai.F(openai.FunctionDefinitionParam{
+			Name:        openai.String(readArtifactName),
+			Description: openai.String("Read a page of a tool output that was saved as an artifact because it was too large."),
+			Parameters: openai.F(openai.FunctionParameters{
+				"type": "object",
+				"properties": map[string]map[string]string{
+					"id":     {"type": "string", "description": "The ID of the artifact."},
+					"offset": {"type": "integer", "description": "The first character to read, 0 by default."},
+					"limit":  {"type": "integer", "description": fmt.Sprintf("The number of characters to read, at most %d.", artifactPageSize)},
+				},
+				"required": []string{"id"},
+			}),
+		}),
+	}
+}
+
+// readArtifact returns a page of an artifact along with the offset of the next one.
+func (s *Swarm) readArtifact(ctx Context, args types.ContextVariables) types.Result {
+	if s.options.ArtifactStore == nil {
+		return types.Result{Value: "Error: no artifact store is configured."}
+	}
+
+	id, _ := args["id"].(string)
+	offset, _ := args["offset"].(float64)
+	limit, _ := args["limit"].(float64)
+	if limit <= 0 || limit > artifactPageSize {
+		limit = artifactPageSize
+	}
+
+	a, err := s.options.ArtifactStore.Load(ctx, runArtifactScope(ctx), id)
+	if errors.Is(err, artifact.ErrNotFound) {
+		return types.Result{Value: fmt.Sprintf("Error: artifact %q not found.", id)}
+	}
+	if err != nil {
+		return types.Result{Value: fmt.Sprintf("Error: %v", err)}
+	}
+
+	content := []rune(a.Content)
+	start := min(max(int(offset), 0), len(content))
+	end := min(start+int(limit), len(content))
+
+	page := fmt.Sprintf("[characters %d-%d of %d]\n%s", start, end, len(content), string(content[start:end]))
+	if end < len(content) {
+		page += fmt.Sprintf("\n[continue with offset %d]", end)
+	}
+	return types.Result{Value: page}
+}
+
+// outputLimit returns the limit of the function, the limit without a function when it has none.
+func outputLimit(agent *types.Agent, name string) (types.OutputLimit, bool) {
+	var fallback *types.OutputLimit
+	for i, limit := range agent.OutputLimits {
+		if limit.Function == nil {
+			if fallback == nil {
+				fallback = &agent.OutputLimits[i]
+			}
+			continue
+		}
+		if functionName(limit.Function) == name {
+			return limit, true
+		}
+	}
+	if fallback == nil {
+		return types.OutputLimit{}, false
+	}
+	return *fallback, true
+}
+
+// limitOutputs shortens the tool messages whose output is above the limit of their function.
+// The pages read with read_artifact are never limited.
+func (s *Swarm) limitOutputs(ctx Context, agent *types.Agent, calls []openai.ChatCompletionMessageToolCall, msgs []openai.ChatCompletionMessageParamUnion, debug bool) []openai.ChatCompletionMessageParamUnion {
+	if len(agent.OutputLimits) == 0 {
+		return msgs
+	}
+
+	names := map[string]string{}
+	for _, call := range calls {
+		names[call.ID] = call.Function.Name
+	}
+
+	limited := make([]openai.ChatCompletionMessageParamUnion, len(msgs))
+	for i, msg := range msgs {
+		limited[i] = msg
+
+		id := history.ToolCallID(msg)
+		name, ok := names[id]
+		if !ok || name == readArtifactName {
+			continue
+		}
+		limit, ok := outputLimit(agent, name)
+		if !ok {
+			continue
+		}
+		output := []rune(history.Text(msg))
+		if len(output) <= limit.MaxChars {
+			continue
+		}
+
+		debugPrint(debug, "Limiting the output of %s: %d characters.", name, len(output))
+		limited[i] = openai.ToolMessage(id, s.limitOutput(ctx, limit, id, name, output, debug))
+	}
+	return limited
+}
+
+func (s *Swarm) limitOutput(ctx Context, limit types.OutputLimit, id, name string, output []rune, debug bool) string {
+	switch limit.Strategy {
+	case types.HeadTailOutput:
+		head := limit.MaxChars / 2
+		tail := limit.MaxChars - head
+		return fmt.Sprintf("%s\n[... %d characters omitted ...]\n%s",
+			string(output[:head]), len(output)-limit.MaxChars, string(output[len(output)-tail:]))
+	case types.SummarizeOutput:
+		if summary, ok := s.summarizeOutput(ctx, limit.Agent, name, string(output), debug); ok {
+			return fmt.Sprintf("[summary of an output of %d characters]\n%s", len(output), summary)
+		}
+	case types.ArtifactOutput:
+		if s.saveArtifact(ctx, id, name, string(output), debug) {
+			return fmt.Sprintf("%s\n[output truncated at %d of %d characters, the full output is saved as artifact %q. Call %s with this id and an offset to read the rest.]",
+				string(output[:limit.MaxChars]), limit.MaxChars, len(output), id, readArtifactName)
+		}
+	}
+
+	return fmt.Sprintf("%s\n[output truncated at %d of %d characters]", string(output[:limit.MaxChars]), limit.MaxChars, len(output))
+}
+
+// summarizeOutput asks the agent for a summary of the output, false when it fails.
+func (s *Swarm) summarizeOutput(ctx Context, agent *types.Agent, name, output string, debug bool) (string, bool) {
+	resp := s.Run(ctx, agent, NewMessages(openai.UserMessage(fmt.Sprintf(outputSummaryPrompt, name, output))),
+		option.WithModel(agent.Model),
+		option.WithMaxTurns(1),
+		option.WithExecuteTools(false),
+		option.WithDebug(debug),
+	)
+	if runError(resp) != nil || finalText(resp) == "" {
+		debugPrint(debug, "Output summary of %s failed, truncating it.", name)
+		return "", false
+	}
+	return finalText(resp), true
+}
+
+// saveArtifact saves the output under the ID of its tool call in the scope of the run, false when
+// there is no store or it fails.
+func (s *Swarm) saveArtifact(ctx Context, id, name, output string, debug bool) bool {
+	if s.options.ArtifactStore == nil {
+		debugPrint(debug, "No artifact store for the output of %s, truncating it.", name)
+		return false
+	}
+
+	err := s.options.ArtifactStore.Save(ctx, &artifact.Artifact{
+		Scope:     runArtifactScope(ctx),
+		ID:        id,
+		Tool:      name,
+		Content:   output,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		debugPrint(debug, "Saving the output of %s failed: %v", name, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package goswarm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/artifact"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/session"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func dumpLogs(ctx goswarm.Context) string {
+	if ctx.IsAnalyze() {
+		ctx.SetDescription("Dump the service logs.")
+		return ""
+	}
+	return "BEGIN" + strings.Repeat("x", 9990) + "END" // 10000 characters once encoded as JSON
+}
+
+func TestOutputLimits(t *testing.T) {
+	const pkg = "github_com/chiwooi/go-swarm_test_"
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if len(req.Messages) == 2 { // the instructions and the question
+			return toolCallReply("call_1", pkg+"dumpLogs", `{}`)
+		}
+		return assistantReply("Done.")
+	})
+
+	for _, tc := range []struct {
+		strategy types.OutputStrategy
+		contains []string
+	}{
+		{types.TruncateOutput, []string{"BEGIN", "truncated at 100 of 10000"}},
+		{types.HeadTailOutput, []string{"BEGIN", "9900 characters omitted", "END"}},
+		// there is no artifact store, the output is truncated
+		{types.ArtifactOutput, []string{"BEGIN", "truncated at 100 of 10000"}},
+	} {
+		agent := goswarm.NewAgent(
+			option.WithAgentFunctions(dumpLogs),
+			option.WithAgentOutputLimit(types.OutputLimit{MaxChars: 100, Strategy: tc.strategy}),
+		)
+
+		resp := client.Run(goswarm.NewContext(context.Background()), agent, goswarm.NewMessages(openai.UserMessage("Show me the logs.")))
+		if resp.Error != nil {
+			t.Fatalf("run failed: %v", resp.Error)
+		}
+
+		output := history.Text(resp.Messages[1])
+		if len(output) > 200 {
+			t.Fatalf("expected the output to be limited, got %d characters", len(output))
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(output, s) {
+				t.Fatalf("expected %q in the limited output, got %q", s, output)
+			}
+		}
+	}
+}
+
+// recordingStore remembers the artifacts saved and the scopes deleted.
+type recordingStore struct {
+	*artifact.MemoryStore
+	saved   []*artifact.Artifact
+	deleted []string
+}
+
+func (s *recordingStore) Save(ctx context.Context, a *artifact.Artifact) error {
+	s.saved = append(s.saved, a)
+	return s.MemoryStore.Save(ctx, a)
+}
+
+func (s *recordingStore) DeleteScope(ctx context.Context, scope string) error {
+	s.deleted = append(s.deleted, scope)
+	return s.MemoryStore.DeleteScope(ctx, scope)
+}
+
+func TestReadArtifact(t *testing.T) {
+	const pkg = "github_com/chiwooi/go-swarm_test_"
+	var toolNames []string
+	turn := 0
+
+	reply := func(req fakeRequest) map[string]any {
+		toolNames = nil
+		for _, tool := range req.Tools {
+			toolNames = append(toolNames, tool["function"].(map[string]any)["name"].(string))
+		}
+
+		switch turn++; turn {
+		case 1:
+			return toolCallReply("call_1", pkg+"dumpLogs", `{}`)
+		case 2:
+			return toolCallReply("call_2", "read_artifact", `{"id": "call_1", "offset": 9000, "limit": 500}`)
+		}
+		return assistantReply("Done.")
+	}
+	store := &recordingStore{MemoryStore: artifact.NewMemoryStore()}
+	client := newFakeSwarm(t, reply, option.WithArtifactStore(store))
+
+	agent := goswarm.NewAgent(
+		option.WithAgentFunctions(dumpLogs),
+		option.WithAgentOutputLimit(types.OutputLimit{MaxChars: 100, Strategy: types.ArtifactOutput}),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+	resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Show me the logs.")))
+	if resp.Error != nil {
+		t.Fatalf("run failed: %v", resp.Error)
+	}
+
+	if len(toolNames) != 2 || toolNames[1] != "read_artifact" {
+		t.Fatalf("expected the read_artifact tool, got %v", toolNames)
+	}
+	if !strings.Contains(history.Text(resp.Messages[1]), `artifact "call_1"`) {
+		t.Fatalf("expected a reference to the artifact, got %q", history.Text(resp.Messages[1]))
+	}
+	if len(store.saved) != 1 || store.saved[0].ID != "call_1" || len(store.saved[0].Content) != 10000 {
+		t.Fatalf("expected the full output in the store, got %d artifacts", len(store.saved))
+	}
+
+	page := history.Text(resp.Messages[3])
+	if !strings.Contains(page, "characters 9000-9500 of 10000") || !strings.Contains(page, "offset 9500") {
+		t.Fatalf("expected the requested page, got %q", page)
+	}
+
+	// the artifacts outlive the run, a later run on the history reads them in the same scope
+	scope := resp.ArtifactScope
+	if len(store.deleted) != 0 || store.saved[0].Scope != scope {
+		t.Fatalf("expected the artifacts to be kept in %q, got %v deleted", scope, store.deleted)
+	}
+	turn = 1
+	messages := append(goswarm.NewMessages(openai.UserMessage("Show me the logs.")), resp.Messages...)
+	resp = client.Run(ctx, agent, append(messages, openai.UserMessage("Show me the end.")), option.WithArtifactScope(scope))
+	if page := history.Text(resp.Messages[1]); !strings.Contains(page, "characters 9000-9500 of 10000") {
+		t.Fatalf("expected the artifact of the previous run, got %q", page)
+	}
+
+	if err := client.DeleteArtifacts(ctx, scope); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, scope, "call_1"); !errors.Is(err, artifact.ErrNotFound) {
+		t.Fatalf("expected the artifact to be gone, got %v", err)
+	}
+
+	// without a store the output is truncated and read_artifact is not offered
+	turn = 0
+	client = newFakeSwarm(t, reply)
+	resp = client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Show me the logs.")))
+	if len(toolNames) != 1 || !strings.Contains(history.Text(resp.Messages[1]), "output truncated at 100") {
+		t.Fatalf("expected a plain truncation without read_artifact, got %v and %q", toolNames, history.Text(resp.Messages[1]))
+	}
+}
+
+func TestSessionArtifacts(t *testing.T) {
+	const pkg = "github_com/chiwooi/go-swarm_test_"
+	var reads []string
+
+	store := &recordingStore{MemoryStore: artifact.NewMemoryStore()}
+	agent := goswarm.NewAgent(
+		option.WithAgentName("Agent"),
+		option.WithAgentFunctions(dumpLogs),
+		option.WithAgentOutputLimit(types.OutputLimit{MaxChars: 100, Strategy: types.ArtifactOutput}),
+	)
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		last := req.Messages[len(req.Messages)-1]
+		switch {
+		case messageText(last) == "Show me the logs.":
+			return toolCallReply("call_1", pkg+"dumpLogs", `{}`)
+		case last["role"] == "user":
+			return toolCallReply("call_2", "read_artifact", `{"id": "call_1"}`)
+		case last["tool_call_id"] == "call_2":
+			reads = append(reads, messageText(last))
+		}
+		return assistantReply("Done.")
+	}, option.WithArtifactStore(store), option.WithSessionStore(session.NewMemoryStore()), option.WithStartAgent(agent))
+
+	ctx := goswarm.NewContext(context.Background())
+	if _, err := client.RunSession(ctx, "s1", "Show me the logs."); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.deleted) != 0 {
+		t.Fatalf("expected the artifacts of the session to be kept, got %v deleted", store.deleted)
+	}
+
+	// a later run of the session reads the artifact, another session does not see it
+	if _, err := client.RunSession(ctx, "s1", "Read it."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RunSession(ctx, "s2", "Read it."); err != nil {
+		t.Fatal(err)
+	}
+	if len(reads) != 2 || !strings.Contains(reads[0], "characters 0-4000 of 10000") || !strings.Contains(reads[1], "not found") {
+		t.Fatalf("expected the artifact to be scoped to its session, got %q", reads)
+	}
+
+	if err := client.DeleteSession(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, store.saved[0].Scope, "call_1"); !errors.Is(err, artifact.ErrNotFound) {
+		t.Fatalf("expected the artifacts to be deleted with the session, got %v", err)
+	}
+}
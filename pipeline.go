@@ -0,0 +1,91 @@
+package goswarm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// RunPipeline runs the stages of the pipeline one after another, starting with the input.
+// The stages share the context variables of ctx. When a stage still fails after its retries, the
+// pipeline stops and returns the trace so far, ResumePipeline retries it from the failed stage.
+func (s *Swarm) RunPipeline(ctx Context, pipeline *types.Pipeline, input string, opts ...option.RunOption) (*types.PipelineResult, error) {
+	return s.ResumePipeline(ctx, pipeline, &types.PipelineResult{Input: input}, opts...)
+}
+
+// ResumePipeline runs the stages that did not succeed yet. The failed stage of the result is run
+// again, the stages before it are kept. The result is updated in place.
+func (s *Swarm) ResumePipeline(ctx Context, pipeline *types.Pipeline, result *types.PipelineResult, opts ...option.RunOption) (*types.PipelineResult, error) {
+	if n := len(result.Stages); n > 0 && result.Stages[n-1].Err != nil {
+		result.Stages = result.Stages[:n-1]
+	}
+	if len(result.Stages) > len(pipeline.Stages) {
+		return result, fmt.Errorf("the result has more stages than pipeline %s", pipeline.Name)
+	}
+
+	for _, stage := range pipeline.Stages[len(result.Stages):] {
+		stageResult := s.runStage(ctx, stage, result, opts)
+		result.Stages = append(result.Stages, stageResult)
+		if stageResult.Err != nil {
+			return result, fmt.Errorf("stage %s: %w", stage.Name, stageResult.Err)
+		}
+	}
+
+	return result, nil
+}
+
+// runStage runs a stage until it succeeds or runs out of retries.
+func (s *Swarm) runStage(ctx Context, stage types.Stage, result *types.PipelineResult, opts []option.RunOption) types.StageResult {
+	args := option.DefRunOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+
+	start := time.Now()
+	stageResult := types.StageResult{Name: stage.Name}
+
+	if stage.When != nil {
+		when, ok := stage.When.(func(Context, *types.PipelineResult) bool)
+		if !ok {
+			stageResult.Err = fmt.Errorf("invalid condition type: %T", stage.When)
+			return stageResult
+		}
+		if !when(ctx, result) {
+			debugPrint(args.Debug, "Skipping stage %s.", stage.Name)
+			stageResult.Skipped = true
+			return stageResult
+		}
+	}
+
+	input := result.Output()
+	if stage.Input != nil {
+		build, ok := stage.Input.(func(Context, *types.PipelineResult) string)
+		if !ok {
+			stageResult.Err = fmt.Errorf("invalid input type: %T", stage.Input)
+			return stageResult
+		}
+		input = build(ctx, result)
+	}
+	stageResult.Input = input
+
+	for stageResult.Attempts = 1; ; stageResult.Attempts++ {
+		resp := s.Run(ctx, stage.Agent, NewMessages(openai.UserMessage(input)), opts...)
+		stageResult.Response = resp
+		stageResult.Err = runError(resp)
+		if stageResult.Err == nil {
+			stageResult.Output = finalText(resp)
+			break
+		}
+		if stageResult.Attempts > stage.MaxRetries {
+			break
+		}
+		debugPrint(args.Debug, "Stage %s failed, retrying: %v", stage.Name, stageResult.Err)
+	}
+
+	stageResult.Duration = time.Since(start)
+	return stageResult
+}
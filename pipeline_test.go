@@ -0,0 +1,79 @@
+package goswarm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestPipeline(t *testing.T) {
+	calls := map[string]int{}
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		name := messageText(req.Messages[0])
+		calls[name]++
+		switch name {
+		case "Extract.":
+			return assistantReply("total=42")
+		case "Write.":
+			return assistantReply("Report: " + messageText(req.Messages[1]))
+		}
+		return assistantReply("unexpected")
+	})
+
+	failures := 1
+	flaky := types.Guardrail{
+		Name: "flaky",
+		Check: func(ctx goswarm.Context, text string) types.GuardrailResult {
+			failures--
+			return types.GuardrailResult{Tripwire: failures >= 0, Reasoning: "temporary failure"}
+		},
+	}
+
+	extractor := goswarm.NewAgent(option.WithAgentInstructions("Extract."))
+	validator := goswarm.NewAgent(option.WithAgentInstructions("Validate."))
+	writer := goswarm.NewAgent(option.WithAgentInstructions("Write."), option.WithAgentOutputGuardrails(flaky))
+
+	pipeline := goswarm.NewPipeline("report",
+		goswarm.NewStage("extract", extractor),
+		goswarm.NewStage("validate", validator, option.WithStageWhen(func(ctx goswarm.Context, r *types.PipelineResult) bool {
+			return ctx.GetVariable("strict", false).(bool)
+		})),
+		goswarm.NewStage("write", writer, option.WithStageInput(func(ctx goswarm.Context, r *types.PipelineResult) string {
+			return ctx.GetVariable("customer", "").(string) + " " + r.Stage("extract").Output
+		})),
+	)
+
+	ctx := goswarm.NewContext(context.Background())
+	ctx.SetVariable("customer", "ACME")
+
+	result, err := client.RunPipeline(ctx, pipeline, "invoice.pdf")
+	if err == nil {
+		t.Fatal("expected the write stage to fail")
+	}
+	if len(result.Stages) != 3 || result.Stages[2].Err == nil || !result.Stages[1].Skipped {
+		t.Fatalf("unexpected trace: %+v", result.Stages)
+	}
+
+	result, err = client.ResumePipeline(ctx, pipeline, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls["Extract."] != 1 || calls["Validate."] != 0 {
+		t.Fatalf("expected only the failed stage to run again, got %v", calls)
+	}
+	if result.Output() != "Report: ACME total=42" {
+		t.Fatalf("unexpected output: %q", result.Output())
+	}
+
+	// retries within a run
+	failures = 1
+	retrying := goswarm.NewPipeline("retry", goswarm.NewStage("write", writer, option.WithStageRetries(1)))
+	result, err = client.RunPipeline(ctx, retrying, "total=1")
+	if err != nil || result.Stages[0].Attempts != 2 {
+		t.Fatalf("expected the stage to succeed on the second attempt, got %v after %d attempts", err, result.Stages[0].Attempts)
+	}
+}
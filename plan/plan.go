@@ -0,0 +1,234 @@
+// Package plan holds the task graph produced by a planner agent and executed by Swarm.PlanAndExecute.
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// Status is the state of a task.
+type Status string
+
+const (
+	Pending Status = "pending"
+	Running Status = "running"
+	Done    Status = "done"
+	Failed  Status = "failed"
+)
+
+// Task is a unit of work assigned to an agent. It runs once the tasks it depends on are done.
+type Task struct {
+	ID          string   `json:"id" desc:"short unique identifier of the task" required:"true"`
+	Description string   `json:"description" desc:"what the agent has to do" required:"true"`
+	Agent       string   `json:"agent" desc:"name of the agent that does the task" required:"true"`
+	DependsOn   []string `json:"depends_on,omitempty" desc:"IDs of the tasks whose output this task needs"`
+
+	Status   Status          `json:"-"`
+	Output   string          `json:"-"`
+	Err      error           `json:"-"`
+	Response *types.Response `json:"-"`
+}
+
+// Plan is a goal broken down into a graph of tasks.
+type Plan struct {
+	Goal     string
+	Tasks    []*Task
+	Revision int // Number of times the plan was revised after a failure
+}
+
+// Task returns the task with the ID, or nil.
+func (p *Plan) Task(id string) *Task {
+	for _, task := range p.Tasks {
+		if task.ID == id {
+			return task
+		}
+	}
+	return nil
+}
+
+// Validate checks that the task IDs are unique and the dependencies exist and have no cycle.
+func (p *Plan) Validate() error {
+	if len(p.Tasks) == 0 {
+		return errors.New("plan has no tasks")
+	}
+
+	for i, task := range p.Tasks {
+		if task.ID == "" {
+			return fmt.Errorf("task %d has no ID", i)
+		}
+		if p.Task(task.ID) != task {
+			return fmt.Errorf("duplicate task %s", task.ID)
+		}
+		for _, dep := range task.DependsOn {
+			if p.Task(dep) == nil {
+				return fmt.Errorf("task %s depends on unknown task %s", task.ID, dep)
+			}
+		}
+	}
+
+	// depth-first search for a path back to a task that is being visited
+	visiting, visited := map[string]bool{}, map[string]bool{}
+	var visit func(task *Task) error
+	visit = func(task *Task) error {
+		if visited[task.ID] {
+			return nil
+		}
+		if visiting[task.ID] {
+			return fmt.Errorf("dependency cycle through task %s", task.ID)
+		}
+		visiting[task.ID] = true
+		for _, dep := range task.DependsOn {
+			if err := visit(p.Task(dep)); err != nil {
+				return err
+			}
+		}
+		visited[task.ID] = true
+		return nil
+	}
+	for _, task := range p.Tasks {
+		if err := visit(task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ready returns the pending tasks whose dependencies are done.
+func (p *Plan) Ready() []*Task {
+	var ready []*Task
+	for _, task := range p.Tasks {
+		if task.Status != Pending {
+			continue
+		}
+
+		ok := true
+		for _, dep := range task.DependsOn {
+			if p.Task(dep).Status != Done {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ready = append(ready, task)
+		}
+	}
+	return ready
+}
+
+// Done reports whether every task is done.
+func (p *Plan) Done() bool {
+	for _, task := range p.Tasks {
+		if task.Status != Done {
+			return false
+		}
+	}
+	return true
+}
+
+// taskRecord is the JSON representation of a task with its status.
+type taskRecord struct {
+	ID          string          `json:"id"`
+	Description string          `json:"description"`
+	Agent       string          `json:"agent"`
+	DependsOn   []string        `json:"depends_on,omitempty"`
+	Status      Status          `json:"status"`
+	Output      string          `json:"output,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Response    *responseRecord `json:"response,omitempty"`
+}
+
+type responseRecord struct {
+	Messages json.RawMessage `json:"messages"` // encoded with history.Marshal
+	Agent    string          `json:"agent,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type planRecord struct {
+	Goal     string       `json:"goal"`
+	Revision int          `json:"revision"`
+	Tasks    []taskRecord `json:"tasks"`
+}
+
+// Marshal encodes the plan with the status and response of every task as JSON.
+// The agents of the responses are stored by name.
+func Marshal(p *Plan) ([]byte, error) {
+	rec := planRecord{Goal: p.Goal, Revision: p.Revision, Tasks: []taskRecord{}}
+
+	for _, task := range p.Tasks {
+		tr := taskRecord{
+			ID:          task.ID,
+			Description: task.Description,
+			Agent:       task.Agent,
+			DependsOn:   task.DependsOn,
+			Status:      task.Status,
+			Output:      task.Output,
+		}
+		if task.Err != nil {
+			tr.Error = task.Err.Error()
+		}
+
+		if resp := task.Response; resp != nil {
+			messages, err := history.Marshal(resp.Messages)
+			if err != nil {
+				return nil, fmt.Errorf("task %s: %w", task.ID, err)
+			}
+			tr.Response = &responseRecord{Messages: messages}
+			if resp.Agent != nil {
+				tr.Response.Agent = resp.Agent.Name
+			}
+			if resp.Error != nil {
+				tr.Response.Error = resp.Error.Error()
+			}
+		}
+
+		rec.Tasks = append(rec.Tasks, tr)
+	}
+
+	return json.Marshal(rec)
+}
+
+// Unmarshal decodes a plan encoded by Marshal. The agents of the responses only keep their name.
+func Unmarshal(data []byte) (*Plan, error) {
+	var rec planRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+
+	p := &Plan{Goal: rec.Goal, Revision: rec.Revision}
+	for _, tr := range rec.Tasks {
+		task := &Task{
+			ID:          tr.ID,
+			Description: tr.Description,
+			Agent:       tr.Agent,
+			DependsOn:   tr.DependsOn,
+			Status:      tr.Status,
+			Output:      tr.Output,
+		}
+		if tr.Error != "" {
+			task.Err = errors.New(tr.Error)
+		}
+
+		if tr.Response != nil {
+			messages, err := history.Unmarshal(tr.Response.Messages)
+			if err != nil {
+				return nil, fmt.Errorf("task %s: %w", tr.ID, err)
+			}
+			task.Response = &types.Response{Messages: messages}
+			if tr.Response.Agent != "" {
+				task.Response.Agent = &types.Agent{Name: tr.Response.Agent}
+			}
+			if tr.Response.Error != "" {
+				task.Response.Error = errors.New(tr.Response.Error)
+			}
+		}
+
+		p.Tasks = append(p.Tasks, task)
+	}
+
+	return p, nil
+}
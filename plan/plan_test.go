@@ -0,0 +1,90 @@
+package plan_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/plan"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func samplePlan() *plan.Plan {
+	return &plan.Plan{
+		Goal: "write a report",
+		Tasks: []*plan.Task{
+			{ID: "a", Agent: "Searcher", Status: plan.Pending},
+			{ID: "b", Agent: "Searcher", Status: plan.Pending},
+			{ID: "c", Agent: "Writer", DependsOn: []string{"a", "b"}, Status: plan.Pending},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := samplePlan()
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Task("a").DependsOn = []string{"c"}
+	if err := p.Validate(); err == nil {
+		t.Fatal("expected a cycle error")
+	}
+
+	p.Task("a").DependsOn = []string{"x"}
+	if err := p.Validate(); err == nil {
+		t.Fatal("expected an unknown dependency error")
+	}
+}
+
+func TestReady(t *testing.T) {
+	p := samplePlan()
+	if ready := p.Ready(); len(ready) != 2 {
+		t.Fatalf("expected the independent tasks to be ready, got %d", len(ready))
+	}
+
+	p.Task("a").Status = plan.Done
+	p.Task("b").Status = plan.Done
+	if ready := p.Ready(); len(ready) != 1 || ready[0].ID != "c" {
+		t.Fatalf("expected the dependent task to be ready, got %+v", ready)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	p := samplePlan()
+	p.Revision = 1
+	a := p.Task("a")
+	a.Status = plan.Done
+	a.Output = "found it"
+	a.Response = &types.Response{
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.AssistantMessage("found it")},
+		Agent:    &types.Agent{Name: "Searcher"},
+	}
+	b := p.Task("b")
+	b.Status = plan.Failed
+	b.Err = errors.New("timeout")
+
+	data, err := plan.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := plan.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Goal != p.Goal || decoded.Revision != 1 || len(decoded.Tasks) != 3 {
+		t.Fatalf("unexpected plan: %+v", decoded)
+	}
+	da := decoded.Task("a")
+	if da.Status != plan.Done || da.Output != "found it" || da.Response.Agent.Name != "Searcher" || len(da.Response.Messages) != 1 {
+		t.Fatalf("unexpected task: %+v", da)
+	}
+	if db := decoded.Task("b"); db.Status != plan.Failed || db.Err == nil || db.Err.Error() != "timeout" {
+		t.Fatalf("unexpected failed task: %+v", db)
+	}
+	if dc := decoded.Task("c"); len(dc.DependsOn) != 2 || dc.Response != nil {
+		t.Fatalf("unexpected pending task: %+v", dc)
+	}
+}
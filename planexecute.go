@@ -0,0 +1,219 @@
+package goswarm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/plan"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+const planPrompt = `Break the goal below down into tasks for the available agents.
+Answer with a JSON object {"Tasks": [{"id": "...", "description": "...", "agent": "...", "depends_on": ["..."]}]}.
+Tasks that do not depend on each other run at the same time. A task gets the outputs of the tasks it depends on.
+
+Available agents:
+%s
+Goal: %s`
+
+const replanPrompt = `The plan for the goal below failed. Plan the remaining work again.
+Tasks that are done keep their output and can be used as dependencies by their ID, do not repeat them.
+Answer with the tasks that still have to run, in the same JSON format.
+
+Plan:
+%s`
+
+// planOutput is the structured output of the planner.
+type planOutput struct {
+	Tasks []*plan.Task `desc:"the tasks of the plan" required:"true"`
+}
+
+// PlanAndExecute asks the planner agent for a plan to reach the goal and runs its tasks as a graph:
+// tasks run concurrently once the tasks they depend on are done, and get their outputs as input.
+// When a task fails, the planner revises the remaining work, at most option.WithMaxReplans times.
+// Tasks work on copies of the context variables. The plan is returned also when it fails.
+func (s *Swarm) PlanAndExecute(ctx Context, planner *types.Agent, goal string, opts ...option.PlanOption) (*plan.Plan, error) {
+	args := option.DefPlanOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
+
+	agents := map[string]*types.Agent{}
+	if s.options.Registry != nil {
+		for _, name := range s.options.Registry.Names() {
+			agents[name], _ = s.options.Registry.Get(name)
+		}
+	}
+	for _, agent := range args.Agents {
+		agents[agent.Name] = agent
+	}
+
+	p := &plan.Plan{Goal: goal}
+
+	tasks, err := s.requestPlan(ctx, planner, fmt.Sprintf(planPrompt, describeAgents(agents), goal), args)
+	if err != nil {
+		return p, err
+	}
+	p.Tasks = tasks
+
+	for {
+		if err := checkPlan(p, agents); err != nil {
+			return p, err
+		}
+
+		failed := s.executePlan(ctx, p, agents, args)
+		if failed == nil {
+			return p, nil
+		}
+		if p.Revision >= args.MaxReplans {
+			return p, fmt.Errorf("task %s: %w", failed.ID, failed.Err)
+		}
+
+		tasks, err := s.requestPlan(ctx, planner, fmt.Sprintf(replanPrompt, describePlan(p)), args)
+		if err != nil {
+			return p, err
+		}
+
+		// the tasks that are done stay, the rest is replaced by the revised tasks
+		var kept []*plan.Task
+		for _, task := range p.Tasks {
+			if task.Status == plan.Done {
+				kept = append(kept, task)
+			}
+		}
+		p.Tasks = append(kept, tasks...)
+		p.Revision++
+	}
+}
+
+// requestPlan runs the planner and returns its tasks.
+func (s *Swarm) requestPlan(ctx Context, planner *types.Agent, prompt string, args option.PlanOptions) ([]*plan.Task, error) {
+	out, _, err := RunTyped[planOutput](s, ctx, planner, NewMessages(openai.UserMessage(prompt)), args.RunOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("planner %s: %w", planner.Name, err)
+	}
+
+	for _, task := range out.Tasks {
+		task.Status = plan.Pending
+	}
+	return out.Tasks, nil
+}
+
+// checkPlan validates the graph of the plan and the agents of its tasks.
+func checkPlan(p *plan.Plan, agents map[string]*types.Agent) error {
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid plan: %w", err)
+	}
+	for _, task := range p.Tasks {
+		if agents[task.Agent] == nil {
+			return fmt.Errorf("invalid plan: task %s is assigned to unknown agent %q", task.ID, task.Agent)
+		}
+	}
+	return nil
+}
+
+// executePlan runs the pending tasks of the plan. After the first failure no new task is started,
+// the running ones are awaited. It returns the failed task, or nil when every task is done.
+func (s *Swarm) executePlan(ctx Context, p *plan.Plan, agents map[string]*types.Agent, args option.PlanOptions) *plan.Task {
+	type taskResult struct {
+		task *plan.Task
+		resp *types.Response
+	}
+
+	results := make(chan taskResult)
+	running := 0
+	var failed *plan.Task
+
+	for {
+		if failed == nil {
+			for _, task := range p.Ready() {
+				task.Status = plan.Running
+				input := taskInput(p, task)
+
+				taskCtx := NewContext(ctx)
+				vars := types.ContextVariables{}
+				for k, v := range ctx.GetVariables() {
+					vars[k] = v
+				}
+				taskCtx.SetVariables(vars)
+
+				running++
+				go func() {
+					resp := s.Run(taskCtx, agents[task.Agent], NewMessages(openai.UserMessage(input)), args.RunOptions...)
+					results <- taskResult{task, resp}
+				}()
+			}
+		}
+
+		if running == 0 {
+			return failed
+		}
+
+		result := <-results
+		running--
+
+		task := result.task
+		task.Response = result.resp
+		if task.Err = runError(result.resp); task.Err != nil {
+			task.Status = plan.Failed
+			if failed == nil {
+				failed = task
+			}
+			continue
+		}
+		task.Status = plan.Done
+		task.Output = finalText(result.resp)
+	}
+}
+
+// taskInput tells the agent of the task about the goal, the task and the outputs it depends on.
+func taskInput(p *plan.Plan, task *plan.Task) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Goal: %s\nYour task: %s\n", p.Goal, task.Description)
+	for _, id := range task.DependsOn {
+		fmt.Fprintf(&sb, "\n## Output of task %s\n%s\n", id, p.Task(id).Output)
+	}
+
+	return sb.String()
+}
+
+func describeAgents(agents map[string]*types.Agent) string {
+	names := make([]string, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		agent := agents[name]
+		if instructions, ok := agent.Instructions.(string); ok {
+			fmt.Fprintf(&sb, "- %s: %s\n", name, instructions)
+		} else {
+			fmt.Fprintf(&sb, "- %s\n", name)
+		}
+	}
+	return sb.String()
+}
+
+func describePlan(p *plan.Plan) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Goal: %s\n", p.Goal)
+	for _, task := range p.Tasks {
+		fmt.Fprintf(&sb, "- %s [%s] %s: %s\n", task.ID, task.Status, task.Agent, task.Description)
+		if task.Status == plan.Done {
+			fmt.Fprintf(&sb, "  output: %s\n", task.Output)
+		}
+		if task.Err != nil {
+			fmt.Fprintf(&sb, "  error: %v\n", task.Err)
+		}
+	}
+
+	return sb.String()
+}
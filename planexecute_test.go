@@ -0,0 +1,101 @@
+package goswarm_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/plan"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestPlanAndExecute(t *testing.T) {
+	var mu sync.Mutex
+	var writerInputs, replanPrompts []string
+	var planFormat map[string]any
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		input := messageText(req.Messages[len(req.Messages)-1])
+		switch messageText(req.Messages[0]) {
+		case "Plan.":
+			planFormat = req.ResponseFormat
+			if strings.Contains(input, "failed") {
+				replanPrompts = append(replanPrompts, input)
+				return assistantReply(`{"Tasks": [{"id": "write2", "description": "write it again", "agent": "Writer", "depends_on": ["weather", "news"]}]}`)
+			}
+			return assistantReply(`{"Tasks": [
+				{"id": "weather", "description": "find the weather", "agent": "Searcher"},
+				{"id": "news", "description": "find the news", "agent": "Searcher"},
+				{"id": "write", "description": "write the report", "agent": "Writer", "depends_on": ["weather", "news"]}
+			]}`)
+		case "Search.":
+			if strings.Contains(input, "weather") {
+				return assistantReply("sunny")
+			}
+			return assistantReply("elections")
+		case "Write.":
+			writerInputs = append(writerInputs, input)
+			return assistantReply("report")
+		}
+		return assistantReply("unexpected")
+	})
+
+	failures := 1
+	flaky := types.Guardrail{
+		Name: "flaky",
+		Check: func(ctx goswarm.Context, text string) types.GuardrailResult {
+			mu.Lock()
+			defer mu.Unlock()
+			failures--
+			return types.GuardrailResult{Tripwire: failures >= 0, Reasoning: "temporary failure"}
+		},
+	}
+
+	planner := goswarm.NewAgent(option.WithAgentName("Planner"), option.WithAgentInstructions("Plan."))
+	searcher := goswarm.NewAgent(option.WithAgentName("Searcher"), option.WithAgentInstructions("Search."))
+	writer := goswarm.NewAgent(option.WithAgentName("Writer"), option.WithAgentInstructions("Write."),
+		option.WithAgentOutputGuardrails(flaky))
+
+	ctx := goswarm.NewContext(context.Background())
+	p, err := client.PlanAndExecute(ctx, planner, "Daily report", option.WithPlanAgents(searcher, writer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Revision != 1 || len(replanPrompts) != 1 {
+		t.Fatalf("expected one revision, got %d", p.Revision)
+	}
+	if !strings.Contains(replanPrompts[0], "weather [done]") || !strings.Contains(replanPrompts[0], "write [failed]") {
+		t.Fatalf("replan prompt does not describe the plan: %s", replanPrompts[0])
+	}
+	if len(p.Tasks) != 3 || p.Task("write2") == nil || !p.Done() {
+		t.Fatalf("unexpected revised plan: %+v", p.Tasks)
+	}
+
+	for _, input := range writerInputs {
+		if !strings.Contains(input, "## Output of task weather\nsunny") || !strings.Contains(input, "## Output of task news\nelections") {
+			t.Fatalf("writer did not get the outputs of its dependencies: %s", input)
+		}
+	}
+	if p.Task("write2").Output != "report" || p.Task("weather").Response == nil {
+		t.Fatalf("unexpected task results: %+v", p.Task("write2"))
+	}
+
+	// the tasks are described with their json names and desc tags, the fields the planner fills in only
+	schema, _ := json.Marshal(planFormat["json_schema"].(map[string]any)["schema"])
+	want := `{"additionalProperties":false,"properties":{"Tasks":{"description":"the tasks of the plan","items":{"additionalProperties":false,"properties":{"agent":{"description":"name of the agent that does the task","type":"string"},"depends_on":{"description":"IDs of the tasks whose output this task needs","items":{"type":"string"},"type":["array","null"]},"description":{"description":"what the agent has to do","type":"string"},"id":{"description":"short unique identifier of the task","type":"string"}},"required":["id","description","agent","depends_on"],"type":"object"},"type":"array"}},"required":["Tasks"],"type":"object"}`
+	if string(schema) != want {
+		t.Fatalf("unexpected plan schema:\n got %s\nwant %s", schema, want)
+	}
+
+	if _, err := plan.Marshal(p); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package redact replaces personal data in the conversation with placeholders before it is sent
+// to the model, and puts the original values back into what the model returns.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+)
+
+// Detector finds one kind of personal data in a text.
+type Detector interface {
+	// Kind names the data in the placeholders, e.g. "EMAIL".
+	Kind() string
+	// Find returns the [start, end) byte offsets of every match.
+	Find(text string) [][]int
+}
+
+// RegexDetector is a Detector backed by a regular expression.
+type RegexDetector struct {
+	Label    string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool // Optional, rejects false positives
+}
+
+func (d *RegexDetector) Kind() string {
+	return d.Label
+}
+
+func (d *RegexDetector) Find(text string) [][]int {
+	var found [][]int
+	for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+		if d.Validate == nil || d.Validate(text[loc[0]:loc[1]]) {
+			found = append(found, loc)
+		}
+	}
+	return found
+}
+
+var (
+	// Email detects e-mail addresses.
+	Email = &RegexDetector{
+		Label:   "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}
+	// Phone detects phone numbers with an optional country code, e.g. +1 (555) 123-4567 or 010-1234-5678.
+	Phone = &RegexDetector{
+		Label:   "PHONE",
+		Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\d{2,4})[ .-]?\d{3,4}[ .-]?\d{4}\b`),
+	}
+	// CardNumber detects payment card numbers that pass the Luhn check.
+	CardNumber = &RegexDetector{
+		Label:    "CARD",
+		Pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Validate: luhn,
+	}
+)
+
+// Defaults returns the built-in detectors. Card numbers come first so that they are not taken for phone numbers.
+func Defaults() []Detector {
+	return []Detector{CardNumber, Email, Phone}
+}
+
+func luhn(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// Vault remembers the values behind the placeholders of a run. The same value always gets the same placeholder.
+type Vault struct {
+	detectors []Detector
+
+	mu           sync.Mutex
+	placeholders map[string]string // value -> placeholder
+	values       map[string]string // placeholder -> value
+	counts       map[string]int
+}
+
+// NewVault creates an empty vault that redacts the data found by the detectors.
+func NewVault(detectors ...Detector) *Vault {
+	return &Vault{
+		detectors:    detectors,
+		placeholders: map[string]string{},
+		values:       map[string]string{},
+		counts:       map[string]int{},
+	}
+}
+
+// Redact replaces the detected values in the text with placeholders such as [EMAIL_1].
+func (v *Vault) Redact(text string) string {
+	type match struct {
+		start, end int
+		kind       string
+	}
+
+	var matches []match
+	for _, detector := range v.detectors {
+		for _, loc := range detector.Find(text) {
+			matches = append(matches, match{loc[0], loc[1], detector.Kind()})
+		}
+	}
+	if len(matches) == 0 {
+		return text
+	}
+
+	// on overlaps the earliest match wins, then the detector listed first
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m.start < last {
+			continue
+		}
+		sb.WriteString(text[last:m.start])
+		sb.WriteString(v.placeholder(m.kind, text[m.start:m.end]))
+		last = m.end
+	}
+	sb.WriteString(text[last:])
+
+	return sb.String()
+}
+
+func (v *Vault) placeholder(kind, value string) string {
+	if p, ok := v.placeholders[value]; ok {
+		return p
+	}
+
+	v.counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", kind, v.counts[kind])
+	v.placeholders[value] = p
+	v.values[p] = value
+	return p
+}
+
+// Rehydrate puts the original values back in place of the placeholders of this vault.
+func (v *Vault) Rehydrate(text string) string {
+	if !strings.Contains(text, "[") {
+		return text
+	}
+
+	v.mu.Lock()
+	pairs := make([]string, 0, 2*len(v.values))
+	for p, value := range v.values {
+		pairs = append(pairs, p, value)
+	}
+	v.mu.Unlock()
+
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// RedactMessage returns a copy of the message with the detected values replaced.
+func (v *Vault) RedactMessage(msg openai.ChatCompletionMessageParamUnion) openai.ChatCompletionMessageParamUnion {
+	return history.MapText(msg, v.Redact)
+}
+
+// RehydrateMessage returns a copy of the message with the original values put back.
+func (v *Vault) RehydrateMessage(msg openai.ChatCompletionMessageParamUnion) openai.ChatCompletionMessageParamUnion {
+	return history.MapText(msg, v.Rehydrate)
+}
+
+// Stream rehydrates a text that arrives in pieces, like the deltas of a streamed completion.
+// A piece that ends inside a placeholder is held back until the placeholder is complete.
+type Stream struct {
+	vault *Vault
+	buf   string
+}
+
+// NewStream creates a stream that puts the values of this vault back.
+func (v *Vault) NewStream() *Stream {
+	return &Stream{vault: v}
+}
+
+// Write adds the next piece of the text and returns the part that can be passed on.
+func (s *Stream) Write(piece string) string {
+	s.buf += piece
+
+	cut := len(s.buf)
+	if i := strings.LastIndexByte(s.buf, '['); i >= 0 && s.vault.isPlaceholderPrefix(s.buf[i:]) {
+		cut = i
+	}
+
+	out := s.vault.Rehydrate(s.buf[:cut])
+	s.buf = s.buf[cut:]
+	return out
+}
+
+// Flush returns the text held back at the end of the stream.
+func (s *Stream) Flush() string {
+	out := s.vault.Rehydrate(s.buf)
+	s.buf = ""
+	return out
+}
+
+// isPlaceholderPrefix tells whether the text is the start of a placeholder but not a whole one.
+func (v *Vault) isPlaceholderPrefix(text string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for p := range v.values {
+		if len(text) < len(p) && strings.HasPrefix(p, text) {
+			return true
+		}
+	}
+	return false
+}
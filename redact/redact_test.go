@@ -0,0 +1,54 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/chiwooi/go-swarm/redact"
+)
+
+func TestVault(t *testing.T) {
+	vault := redact.NewVault(redact.Defaults()...)
+
+	text := "Mail bob@example.com or call +1 (555) 123-4567, card 4111 1111 1111 1111. Again: bob@example.com"
+	redacted := vault.Redact(text)
+
+	want := "Mail [EMAIL_1] or call [PHONE_1], card [CARD_1]. Again: [EMAIL_1]"
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+	if got := vault.Rehydrate(redacted); got != text {
+		t.Fatalf("unexpected rehydration: %q", got)
+	}
+
+	// placeholders are stable within the vault
+	if got := vault.Redact("from bob@example.com"); got != "from [EMAIL_1]" {
+		t.Fatalf("expected the same placeholder, got %q", got)
+	}
+}
+
+func TestCardNumberLuhn(t *testing.T) {
+	vault := redact.NewVault(redact.CardNumber)
+
+	if got := vault.Redact("order 1234 5678 9012 3456"); got != "order 1234 5678 9012 3456" {
+		t.Fatalf("expected an invalid card number to be kept, got %q", got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	vault := redact.NewVault(redact.Email)
+	vault.Redact("bob@example.com")
+
+	stream := vault.NewStream()
+	var got string
+	for _, piece := range []string{"Write to [EM", "AIL", "_1] or [", "x]. [EMAIL_"} {
+		got += stream.Write(piece)
+	}
+	if got != "Write to bob@example.com or [x]. " {
+		t.Fatalf("unexpected stream output: %q", got)
+	}
+
+	// an unfinished placeholder is passed on as is at the end
+	if rest := stream.Flush(); rest != "[EMAIL_" {
+		t.Fatalf("unexpected flush: %q", rest)
+	}
+}
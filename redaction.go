@@ -0,0 +1,126 @@
+package goswarm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/redact"
+)
+
+type vaultKey struct{}
+
+// withRedaction attaches a new vault to the context of a run when redaction is enabled.
+// Nested runs, like summaries and guardrail classifiers, share the vault of the outer run.
+func (s *Swarm) withRedaction(ctx Context) Context {
+	if len(s.options.Redaction) == 0 || getVault(ctx) != nil {
+		return ctx
+	}
+	return NewContext(context.WithValue(ctx, vaultKey{}, redact.NewVault(s.options.Redaction...)))
+}
+
+func getVault(ctx Context) *redact.Vault {
+	vault, _ := ctx.Value(vaultKey{}).(*redact.Vault)
+	return vault
+}
+
+// rehydrate puts the values redacted from the request back into the reply of the model,
+// so that tools and the caller only ever see the original values.
+func rehydrate(ctx Context, message openai.ChatCompletionMessage) openai.ChatCompletionMessage {
+	if vault := getVault(ctx); vault != nil {
+		return vault.RehydrateMessage(message).(openai.ChatCompletionMessage)
+	}
+	return message
+}
+
+// chunkRehydrator puts the redacted values back into the deltas of a streamed completion.
+// Every text of the completion, the content and the arguments of each tool call, has its own stream.
+type chunkRehydrator struct {
+	vault   *redact.Vault
+	streams map[[3]int64]*redact.Stream
+	last    openai.ChatCompletionChunk
+}
+
+func newChunkRehydrator(ctx Context) *chunkRehydrator {
+	return &chunkRehydrator{vault: getVault(ctx), streams: map[[3]int64]*redact.Stream{}}
+}
+
+func (r *chunkRehydrator) write(key [3]int64, piece string) string {
+	stream, ok := r.streams[key]
+	if !ok {
+		stream = r.vault.NewStream()
+		r.streams[key] = stream
+	}
+	return stream.Write(piece)
+}
+
+// chunk returns a copy of the chunk with the values put back, held back parts of placeholders are left out.
+func (r *chunkRehydrator) chunk(chunk openai.ChatCompletionChunk) openai.ChatCompletionChunk {
+	if r.vault == nil {
+		return chunk
+	}
+	r.last = chunk
+
+	choices := make([]openai.ChatCompletionChunkChoice, len(chunk.Choices))
+	for i, choice := range chunk.Choices {
+		choice.Delta.Content = r.write([3]int64{choice.Index, -1, 0}, choice.Delta.Content)
+		choice.Delta.Refusal = r.write([3]int64{choice.Index, -1, 1}, choice.Delta.Refusal)
+
+		calls := make([]openai.ChatCompletionChunkChoicesDeltaToolCall, len(choice.Delta.ToolCalls))
+		for j, call := range choice.Delta.ToolCalls {
+			call.Function.Arguments = r.write([3]int64{choice.Index, call.Index, 0}, call.Function.Arguments)
+			calls[j] = call
+		}
+		if choice.Delta.ToolCalls != nil {
+			choice.Delta.ToolCalls = calls
+		}
+		choices[i] = choice
+	}
+	chunk.Choices = choices
+
+	return chunk
+}
+
+// flush returns a chunk with the text still held back at the end of the completion, if there is any.
+func (r *chunkRehydrator) flush() (openai.ChatCompletionChunk, bool) {
+	chunk := openai.ChatCompletionChunk{
+		ID:      r.last.ID,
+		Created: r.last.Created,
+		Model:   r.last.Model,
+		Object:  r.last.Object,
+	}
+
+	choices := map[int64]*openai.ChatCompletionChunkChoice{}
+	for key, stream := range r.streams {
+		rest := stream.Flush()
+		if rest == "" {
+			continue
+		}
+
+		choice, ok := choices[key[0]]
+		if !ok {
+			choice = &openai.ChatCompletionChunkChoice{Index: key[0]}
+			choices[key[0]] = choice
+		}
+		switch {
+		case key[1] >= 0:
+			choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, openai.ChatCompletionChunkChoicesDeltaToolCall{
+				Index:    key[1],
+				Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Arguments: rest},
+			})
+		case key[2] == 1:
+			choice.Delta.Refusal = rest
+		default:
+			choice.Delta.Content = rest
+		}
+	}
+	for _, choice := range choices {
+		calls := choice.Delta.ToolCalls
+		sort.Slice(calls, func(i, j int) bool { return calls[i].Index < calls[j].Index })
+		chunk.Choices = append(chunk.Choices, *choice)
+	}
+	sort.Slice(chunk.Choices, func(i, j int) bool { return chunk.Choices[i].Index < chunk.Choices[j].Index })
+
+	return chunk, len(chunk.Choices) > 0
+}
@@ -0,0 +1,123 @@
+package goswarm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	oaioption "github.com/openai/openai-go/option"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+type emailArgs struct {
+	Email string `desc:"customer e-mail" required:"true"`
+}
+
+var mailed []string
+
+func SendReceipt(ctx goswarm.Context, args emailArgs) string {
+	if !ctx.IsAnalyze() {
+		mailed = append(mailed, args.Email)
+	}
+	return "sent to " + args.Email
+}
+
+func TestRedaction(t *testing.T) {
+	var requests []string
+	mailed = nil
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		data, _ := json.Marshal(req.Messages)
+		requests = append(requests, string(data))
+		if len(requests) == 1 {
+			return toolCallReply("call_1", req.Tools[0]["function"].(map[string]any)["name"].(string), `{"Email":"[EMAIL_1]"}`)
+		}
+		return assistantReply("I sent the receipt to [EMAIL_1].")
+	}, option.WithRedaction())
+
+	agent := goswarm.NewAgent(option.WithAgentFunctions(SendReceipt))
+	ctx := goswarm.NewContext(context.Background())
+
+	resp := client.Run(ctx, agent, goswarm.NewMessages(openai.UserMessage("Send my receipt to jane@example.com")))
+
+	for i, req := range requests {
+		if strings.Contains(req, "jane@example.com") {
+			t.Fatalf("request %d contains the clear e-mail: %s", i, req)
+		}
+	}
+	if len(mailed) != 1 || mailed[0] != "jane@example.com" {
+		t.Fatalf("expected the tool to get the clear e-mail, got %v", mailed)
+	}
+
+	final := resp.Messages[len(resp.Messages)-1].(openai.ChatCompletionMessage).Content
+	if final != "I sent the receipt to jane@example.com." {
+		t.Fatalf("expected a rehydrated answer, got %q", final)
+	}
+}
+
+func TestRedactionStream(t *testing.T) {
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(data))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		// the placeholder is split across chunks
+		for _, delta := range []string{"I will write to [EM", "AIL_1", "]. Bye [EMAIL_"} {
+			data, _ := json.Marshal(map[string]any{
+				"id": "chatcmpl-test", "object": "chat.completion.chunk", "created": 0, "model": "gpt-4o",
+				"choices": []any{map[string]any{"index": 0, "delta": map[string]any{"role": "assistant", "content": delta}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := goswarm.NewSwarm(openai.NewClient(
+		oaioption.WithBaseURL(srv.URL+"/"),
+		oaioption.WithAPIKey("test"),
+		oaioption.WithMaxRetries(0),
+	), option.WithRedaction())
+
+	ctx := goswarm.NewContext(context.Background())
+	stream := client.RunAndStream(ctx, goswarm.NewAgent(), goswarm.NewMessages(openai.UserMessage("Mail jane@example.com")))
+
+	var streamed string
+	var resp *types.Response
+	for event := range stream {
+		switch v := event.(type) {
+		case openai.ChatCompletionChunk:
+			for _, choice := range v.Choices {
+				if strings.Contains(choice.Delta.Content, "[EMAIL") && !strings.HasSuffix(choice.Delta.Content, "[EMAIL_") {
+					t.Fatalf("chunk passed on a placeholder: %q", choice.Delta.Content)
+				}
+				streamed += choice.Delta.Content
+			}
+		case *types.Response:
+			resp = v
+		}
+	}
+
+	if strings.Contains(requests[0], "jane@example.com") {
+		t.Fatalf("the request contains the clear e-mail: %s", requests[0])
+	}
+	// the unfinished placeholder at the end is passed on as is
+	if streamed != "I will write to jane@example.com. Bye [EMAIL_" {
+		t.Fatalf("unexpected streamed text: %q", streamed)
+	}
+	if resp == nil || history.Text(resp.Messages[0]) != streamed {
+		t.Fatalf("expected the response to match the stream, got %+v", resp)
+	}
+}
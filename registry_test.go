@@ -0,0 +1,150 @@
+package goswarm_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestDeriveAgent(t *testing.T) {
+	lookup := func() string { return "found" }
+	refund := func() string { return "refunded" }
+
+	base := goswarm.NewAgent(
+		option.WithAgentName("Support"),
+		option.WithAgentModel("gpt-4o"),
+		option.WithAgentFunctions(lookup),
+	)
+	tenant := goswarm.DeriveAgent(base,
+		option.WithAgentName("Support (acme)"),
+		option.WithAgentModel("gpt-4o-mini"),
+		option.WithAgentFunctions(refund),
+	)
+
+	if base.Name != "Support" || base.Model != "gpt-4o" || len(base.Functions) != 1 {
+		t.Fatalf("expected the base agent to stay unchanged, got %+v", base)
+	}
+	if tenant.Name != "Support (acme)" || tenant.Model != "gpt-4o-mini" || len(tenant.Functions) != 2 {
+		t.Fatalf("unexpected derived agent: %+v", tenant)
+	}
+	if tenant.Instructions != base.Instructions {
+		t.Fatalf("expected the instructions to be inherited, got %v", tenant.Instructions)
+	}
+
+	clone := base.Clone()
+	clone.Functions[0] = nil
+	if base.Functions[0] == nil {
+		t.Fatal("expected the clone not to share the functions of the base agent")
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	sales := goswarm.NewAgent(option.WithAgentName("Sales"))
+	triage := goswarm.NewAgent(
+		option.WithAgentName("Triage"),
+		option.WithAgentHandoffs(sales),
+	)
+
+	registry, err := goswarm.NewRegistry(triage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(); err == nil {
+		t.Fatal("expected the unregistered handoff target to be reported")
+	}
+
+	if err := registry.Register(goswarm.DeriveAgent(sales)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Validate(); err == nil {
+		t.Fatal("expected the other agent with the same name to be reported")
+	}
+
+	registry, _ = goswarm.NewRegistry(triage, sales)
+	if err := registry.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(goswarm.DeriveAgent(sales)); err == nil {
+		t.Fatal("expected the duplicate name to be rejected")
+	}
+	if err := registry.Register(goswarm.DeriveAgent(sales, option.WithAgentName(""))); err == nil {
+		t.Fatal("expected the empty name to be rejected")
+	}
+
+	// an invalid agent keeps the others from being registered
+	billing := goswarm.NewAgent(option.WithAgentName("Billing"))
+	if err := registry.Register(billing, nil); err == nil {
+		t.Fatal("expected the nil agent to be rejected")
+	}
+	if err := registry.Register(billing, goswarm.DeriveAgent(billing)); err == nil {
+		t.Fatal("expected the duplicate name within the arguments to be rejected")
+	}
+	if _, ok := registry.Get("Billing"); ok {
+		t.Fatal("expected no agent to be registered by a failed Register")
+	}
+}
+
+type keepAll struct{ name string }
+
+func (s keepAll) Apply(msgs []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	return msgs
+}
+
+func TestDeriveAgentKeepsEveryField(t *testing.T) {
+	temperature := 0.5
+	sub := &types.Agent{Name: "Sub"}
+
+	// values that compare with reflect.DeepEqual, functions never do
+	base := &types.Agent{
+		Name:              "Support",
+		Model:             "gpt-4o",
+		Instructions:      "Help.",
+		Functions:         []types.AgentFunction{"lookup"},
+		ToolChoice:        openai.ChatCompletionToolChoiceOptionBehaviorAuto,
+		ParallelToolCalls: true,
+		HistoryStrategy:   keepAll{"all"},
+		Summarization:     &types.Summarization{Agent: sub, ContextWindow: 1000},
+		ApprovalRequired:  []types.AgentFunction{"lookup"},
+		InputGuardrails:   []types.Guardrail{{Name: "input"}},
+		OutputGuardrails:  []types.Guardrail{{Name: "output"}},
+		OutputType:        reflect.TypeOf(struct{}{}),
+		ModelSettings: types.ModelSettings{
+			Temperature: &temperature,
+			Stop:        []string{"END"},
+			LogitBias:   map[string]int64{"50256": -100},
+			Metadata:    map[string]string{"tenant": "acme"},
+		},
+		ToolPredicates: []types.ToolPredicate{{Function: "lookup"}},
+		OutputLimits:   []types.OutputLimit{{MaxChars: 100}},
+	}
+
+	v := reflect.ValueOf(base).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).IsZero() {
+			t.Fatalf("set %s in the test so that DeriveAgent is checked to keep it", v.Type().Field(i).Name)
+		}
+	}
+
+	derived := reflect.ValueOf(goswarm.DeriveAgent(base)).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !reflect.DeepEqual(v.Field(i).Interface(), derived.Field(i).Interface()) {
+			t.Errorf("DeriveAgent lost %s: %v", v.Type().Field(i).Name, derived.Field(i).Interface())
+		}
+	}
+
+	clone := base.Clone()
+	*clone.ModelSettings.Temperature = 1
+	clone.ModelSettings.Stop[0] = "STOP"
+	clone.ModelSettings.LogitBias["50256"] = 0
+	clone.ModelSettings.Metadata["tenant"] = "other"
+	clone.Summarization.ContextWindow = 2000
+	if temperature != 0.5 || base.ModelSettings.Stop[0] != "END" || base.ModelSettings.LogitBias["50256"] != -100 ||
+		base.ModelSettings.Metadata["tenant"] != "acme" || base.Summarization.ContextWindow != 1000 {
+		t.Fatalf("expected the clone not to share the settings of the base agent, got %+v", base.ModelSettings)
+	}
+}
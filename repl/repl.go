@@ -1,6 +1,7 @@
 package repl
 
 import (
+    "errors"
     "fmt"
     "bufio"
     "os"
@@ -9,6 +10,7 @@ import (
     "github.com/openai/openai-go"
 
     "github.com/chiwooi/go-swarm"
+    "github.com/chiwooi/go-swarm/history"
     "github.com/chiwooi/go-swarm/option"
     "github.com/chiwooi/go-swarm/types"
 )
@@ -77,18 +79,14 @@ func ProcessAndPrintStreamingResponse(response <-chan any) *types.Response {
 }
 
 func convertToMessage(param openai.ChatCompletionMessageParamUnion) openai.ChatCompletionMessage {
-    switch v := param.(type) {
-    case openai.ChatCompletionMessage:
+    if v, ok := param.(openai.ChatCompletionMessage); ok {
         return v
-    case openai.ChatCompletionMessageParam:
-        return openai.ChatCompletionMessage{
-            Role:         openai.ChatCompletionMessageRole(v.Role.Value),
-            Content:      v.Content.String(),
-            FunctionCall: v.FunctionCall.Value.(openai.ChatCompletionMessageFunctionCall),
-            ToolCalls:    v.ToolCalls.Value.([]openai.ChatCompletionMessageToolCall),
-        }
     }
-    return openai.ChatCompletionMessage{}
+    return openai.ChatCompletionMessage{
+        Role:      openai.ChatCompletionMessageRole(history.Role(param)),
+        Content:   history.Text(param),
+        ToolCalls: history.ToolCalls(param),
+    }
 }
 
 func PrettyPrintMessages(messages []openai.ChatCompletionMessageParamUnion) {
@@ -121,6 +119,39 @@ func PrettyPrintMessages(messages []openai.ChatCompletionMessageParamUnion) {
 }
 
 
+// parseUserInput turns the input into a user message. Words like @path/to/image.png attach the
+// image (png, jpeg, gif, webp) or audio (wav, mp3) file at that path. Other files are rejected with
+// goswarm.ErrFileParts, words that do not name a file (e.g. @jane) are kept as text.
+func parseUserInput(input string) (openai.ChatCompletionMessageParamUnion, error) {
+    var words []string
+    var parts []openai.ChatCompletionContentPartUnionParam
+
+    for _, word := range strings.Fields(input) {
+        path, ok := strings.CutPrefix(word, "@")
+        if !ok || path == "" {
+            words = append(words, word)
+            continue
+        }
+
+        part, err := goswarm.AttachFile(path)
+        if errors.Is(err, goswarm.ErrFileParts) {
+            if _, statErr := os.Stat(path); statErr != nil {
+                words = append(words, word)
+                continue
+            }
+        }
+        if err != nil {
+            return nil, err
+        }
+        parts = append(parts, part)
+    }
+
+    if len(parts) == 0 {
+        return openai.UserMessage(input), nil
+    }
+    return goswarm.UserMessage(strings.Join(words, " "), parts...), nil
+}
+
 func RunDemoLoop(ctx goswarm.Context, startAgent *types.Agent, opts ...option.RunOption) {
     args := option.DefRunOptions
     for _, opt := range opts {
@@ -141,7 +172,12 @@ func RunDemoLoop(ctx goswarm.Context, startAgent *types.Agent, opts ...option.Ru
         userInput, _ := reader.ReadString('\n')
         userInput = strings.ReplaceAll(userInput, "\n", "")
 
-        messages = append(messages, openai.UserMessage(userInput))
+        message, err := parseUserInput(userInput)
+        if err != nil {
+            fmt.Printf("\033[91mError\033[0m: %v\n", err)
+            continue
+        }
+        messages = append(messages, message)
 
         var response *types.Response
 
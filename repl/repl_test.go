@@ -0,0 +1,74 @@
+package repl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+)
+
+// a 1x1 transparent png
+var pixel = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func TestParseUserInput(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{"pixel.png": pixel, "voice.wav": []byte("RIFF"), "invoice.pdf": []byte("%PDF")} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		text  string
+		media []string // types of the attached parts
+		err   error
+	}{
+		{name: "text", input: "Hello there", text: "Hello there"},
+		{name: "mention", input: "Ask @jane about @example.com", text: "Ask @jane about @example.com"},
+		{name: "bare at", input: "Meet @ noon", text: "Meet @ noon"},
+		{name: "image", input: "What is @" + dir + "/pixel.png ?", text: "What is ?", media: []string{"image_url"}},
+		{name: "image and audio", input: "@" + dir + "/pixel.png @" + dir + "/voice.wav", media: []string{"image_url", "input_audio"}},
+		{name: "file part", input: "Sum up @" + dir + "/invoice.pdf", err: goswarm.ErrFileParts},
+		{name: "missing image", input: "@" + dir + "/missing.png", err: os.ErrNotExist},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := parseUserInput(tc.input)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if history.Role(msg) != "user" || history.Text(msg) != tc.text {
+				t.Fatalf("expected the user message %q, got %q", tc.text, history.Text(msg))
+			}
+			media := history.Media(msg)
+			if len(media) != len(tc.media) {
+				t.Fatalf("expected %d attachments, got %d", len(tc.media), len(media))
+			}
+			for i, part := range media {
+				var kind string
+				switch part.(type) {
+				case openai.ChatCompletionContentPartImageParam:
+					kind = "image_url"
+				case openai.ChatCompletionContentPartInputAudioParam:
+					kind = "input_audio"
+				}
+				if kind != tc.media[i] {
+					t.Fatalf("expected a %s part, got %T", tc.media[i], part)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package goswarm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/session"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// RunSession continues the conversation stored under sessionID with a new user message.
+// The history, the active agent and the context variables are loaded from the session store of the
+// Swarm and saved back after the run. When another run saved the same session in the meantime,
+// session.ErrConflict is returned and the stored session is left untouched. A run stopped by a
+// guardrail is not saved either, the rejected messages do not become part of the session.
+func (s *Swarm) RunSession(ctx Context, sessionID string, userMessage string, opts ...option.RunOption) (*types.Response, error) {
+	store := s.options.SessionStore
+	if store == nil {
+		return nil, errors.New("no session store configured, use option.WithSessionStore")
+	}
+
+	sess, err := store.Load(ctx, sessionID)
+	if errors.Is(err, session.ErrNotFound) {
+		sess = session.New(sessionID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	agent, err := s.sessionAgent(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history.PendingToolCalls(sess.Messages)) > 0 {
+		return nil, fmt.Errorf("session %s is waiting for approval, use ContinueSession", sessionID)
+	}
+
+	runCtx := NewContext(ctx)
+	runCtx.SetVariables(sess.Variables)
+
+	messages := append([]openai.ChatCompletionMessageParamUnion{}, sess.Messages...)
+	messages = append(messages, openai.UserMessage(userMessage))
+
+	opts = append([]option.RunOption{option.WithHistorySummary(sess.Summary)}, opts...)
+	opts = append(opts, option.WithArtifactScope(sessionArtifactScope(sessionID)))
+	resp := s.Run(runCtx, agent, messages, opts...)
+
+	return resp, s.saveSession(ctx, sess, runCtx, messages, resp)
+}
+
+// ContinueSession continues the session stored under sessionID after its last run paused for
+// approval, see Continue. ErrNoPendingToolCalls is returned when the session does not wait.
+func (s *Swarm) ContinueSession(ctx Context, sessionID string, decisions []types.ApprovalDecision, opts ...option.RunOption) (*types.Response, error) {
+	store := s.options.SessionStore
+	if store == nil {
+		return nil, errors.New("no session store configured, use option.WithSessionStore")
+	}
+
+	sess, err := store.Load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := s.sessionAgent(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx := NewContext(ctx)
+	runCtx.SetVariables(sess.Variables)
+
+	messages := append([]openai.ChatCompletionMessageParamUnion{}, sess.Messages...)
+
+	opts = append([]option.RunOption{option.WithHistorySummary(sess.Summary)}, opts...)
+	opts = append(opts, option.WithArtifactScope(sessionArtifactScope(sessionID)))
+	resp := s.Continue(runCtx, agent, messages, decisions, opts...)
+	if errors.Is(resp.Error, ErrNoPendingToolCalls) {
+		return nil, resp.Error
+	}
+
+	return resp, s.saveSession(ctx, sess, runCtx, messages, resp)
+}
+
+// saveSession stores the messages of the run, the active agent, the context variables and the
+// running summary in the session. The session keeps its prior state when a guardrail tripped.
+func (s *Swarm) saveSession(ctx Context, sess *session.Session, runCtx Context, messages []openai.ChatCompletionMessageParamUnion, resp *types.Response) error {
+	var tripped *types.GuardrailError
+	if errors.As(resp.Error, &tripped) {
+		return nil
+	}
+
+	sess.Messages = append(messages, resp.Messages...)
+	sess.AgentName = resp.Agent.Name
+	sess.Variables = runCtx.GetVariables()
+	sess.Summary = resp.Summary
+
+	return s.options.SessionStore.Save(ctx, sess)
+}
+
+// DeleteSession removes the session stored under sessionID and the tool outputs its runs saved as artifacts.
+func (s *Swarm) DeleteSession(ctx Context, sessionID string) error {
+	store := s.options.SessionStore
+	if store == nil {
+		return errors.New("no session store configured, use option.WithSessionStore")
+	}
+
+	if s.options.ArtifactStore != nil {
+		if err := s.options.ArtifactStore.DeleteScope(ctx, sessionArtifactScope(sessionID)); err != nil {
+			return err
+		}
+	}
+	return store.Delete(ctx, sessionID)
+}
+
+// sessionArtifactScope is the artifact scope of a session, its artifacts stay readable in later runs.
+func sessionArtifactScope(sessionID string) string {
+	return "session/" + sessionID
+}
+
+// sessionAgent resolves the active agent of the session, new sessions start with the start agent.
+func (s *Swarm) sessionAgent(sess *session.Session) (*types.Agent, error) {
+	if sess.AgentName == "" {
+		if s.options.StartAgent == nil {
+			return nil, errors.New("no start agent configured, use option.WithStartAgent")
+		}
+		return s.options.StartAgent, nil
+	}
+
+	agent, err := s.lookupAgent(sess.AgentName)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", sess.ID, err)
+	}
+	return agent, nil
+}
+
+// lookupAgent resolves an agent by name from the registry or the start agent of the Swarm.
+func (s *Swarm) lookupAgent(name string) (*types.Agent, error) {
+	if s.options.Registry != nil {
+		if agent, ok := s.options.Registry.Get(name); ok {
+			return agent, nil
+		}
+	}
+	if start := s.options.StartAgent; start != nil && start.Name == name {
+		return start, nil
+	}
+
+	return nil, fmt.Errorf("agent %q is not registered", name)
+}
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var sessionsBucket = []byte("sessions")
+
+// BoltStore keeps sessions in an embedded bbolt key-value database.
+// The version check and the write happen in a single transaction.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the database file at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Load(ctx context.Context, id string) (*Session, error) {
+	var sess *Session
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(sessionsBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		sess, err = decode(data)
+		return err
+	})
+
+	return sess, err
+}
+
+func (s *BoltStore) Save(ctx context.Context, sess *Session) error {
+	next := *sess
+	next.Version++
+	next.UpdatedAt = time.Now()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+
+		var version int64
+		if data := bucket.Get([]byte(sess.ID)); data != nil {
+			stored, err := decode(data)
+			if err != nil {
+				return err
+			}
+			version = stored.Version
+		}
+		if version != sess.Version {
+			return ErrConflict
+		}
+
+		data, err := encode(&next)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(sess.ID), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	sess.Version, sess.UpdatedAt = next.Version, next.UpdatedAt
+	return nil
+}
+
+func (s *BoltStore) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	})
+}
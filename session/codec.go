@@ -0,0 +1,69 @@
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// record is the JSON representation of a session used by the file and bolt stores.
+type record struct {
+	ID        string                 `json:"id"`
+	Messages  json.RawMessage        `json:"messages"` // encoded with history.Marshal
+	AgentName string                 `json:"agent"`
+	Variables types.ContextVariables `json:"variables"`
+	Summary   types.HistorySummary   `json:"summary"`
+	Version   int64                  `json:"version"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+func encode(sess *Session) ([]byte, error) {
+	messages, err := history.Marshal(sess.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(record{
+		ID:        sess.ID,
+		Messages:  messages,
+		AgentName: sess.AgentName,
+		Variables: sess.Variables,
+		Summary:   sess.Summary,
+		Version:   sess.Version,
+		UpdatedAt: sess.UpdatedAt,
+	})
+}
+
+func decode(data []byte) (*Session, error) {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+
+	sess := &Session{
+		ID:        rec.ID,
+		Messages:  []openai.ChatCompletionMessageParamUnion{},
+		AgentName: rec.AgentName,
+		Variables: rec.Variables,
+		Summary:   rec.Summary,
+		Version:   rec.Version,
+		UpdatedAt: rec.UpdatedAt,
+	}
+	if sess.Variables == nil {
+		sess.Variables = types.ContextVariables{}
+	}
+
+	if len(rec.Messages) > 0 {
+		messages, err := history.Unmarshal(rec.Messages)
+		if err != nil {
+			return nil, err
+		}
+		sess.Messages = messages
+	}
+
+	return sess, nil
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore keeps every session as a JSON file in a directory.
+// Concurrent saves are serialized within the process only.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore creates a FileStore writing into dir, which is created when missing.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
+
+func (s *FileStore) Load(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(id)
+}
+
+func (s *FileStore) load(id string) (*Session, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", id, err)
+	}
+	return sess, nil
+}
+
+func (s *FileStore) Save(ctx context.Context, sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var version int64
+	stored, err := s.load(sess.ID)
+	switch {
+	case err == nil:
+		version = stored.Version
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	if version != sess.Version {
+		return ErrConflict
+	}
+
+	next := *sess
+	next.Version++
+	next.UpdatedAt = time.Now()
+
+	data, err := encode(&next)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash never leaves a partial session behind
+	tmp := s.path(sess.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(sess.ID)); err != nil {
+		return err
+	}
+
+	sess.Version, sess.UpdatedAt = next.Version, next.UpdatedAt
+	return nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// MemoryStore keeps sessions in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]*Session{}}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(sess), nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, sess *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var version int64
+	if stored, ok := s.sessions[sess.ID]; ok {
+		version = stored.Version
+	}
+	if version != sess.Version {
+		return ErrConflict
+	}
+
+	sess.Version++
+	sess.UpdatedAt = time.Now()
+	s.sessions[sess.ID] = clone(sess)
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// clone copies the session so that callers can't modify the stored state.
+func clone(sess *Session) *Session {
+	c := *sess
+	c.Messages = append([]openai.ChatCompletionMessageParamUnion{}, sess.Messages...)
+	c.Variables = types.ContextVariables{}
+	for k, v := range sess.Variables {
+		c.Variables[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,47 @@
+// Package session persists multi-turn conversations between calls to Swarm.RunSession.
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+var (
+	// ErrNotFound is returned by Store.Load when no session is stored under the ID.
+	ErrNotFound = errors.New("session: not found")
+	// ErrConflict is returned by Store.Save when the session was modified since it was loaded.
+	ErrConflict = errors.New("session: version conflict")
+)
+
+// Session is the state of a conversation carried between runs.
+type Session struct {
+	ID        string
+	Messages  []openai.ChatCompletionMessageParamUnion
+	AgentName string // Name of the active agent, resolved from a types.Registry
+	Variables types.ContextVariables
+	Summary   types.HistorySummary // Running summary of Messages, see option.WithHistorySummary
+	Version   int64                // Incremented by every successful Save, 0 for a session that was never saved
+	UpdatedAt time.Time
+}
+
+// New creates an empty session.
+func New(id string) *Session {
+	return &Session{
+		ID:        id,
+		Messages:  []openai.ChatCompletionMessageParamUnion{},
+		Variables: types.ContextVariables{},
+	}
+}
+
+// Store persists sessions with optimistic concurrency: Save only succeeds when the stored version
+// still equals the version of the session passed in, and increments it.
+type Store interface {
+	Load(ctx context.Context, id string) (*Session, error)
+	Save(ctx context.Context, sess *Session) error
+	Delete(ctx context.Context, id string) error
+}
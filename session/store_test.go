@@ -0,0 +1,83 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/session"
+)
+
+func stores(t *testing.T) map[string]session.Store {
+	dir := t.TempDir()
+
+	fileStore, err := session.NewFileStore(filepath.Join(dir, "files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boltStore, err := session.NewBoltStore(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { boltStore.Close() })
+
+	return map[string]session.Store{
+		"memory": session.NewMemoryStore(),
+		"file":   fileStore,
+		"bolt":   boltStore,
+	}
+}
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Load(ctx, "s1"); !errors.Is(err, session.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+
+			sess := session.New("s1")
+			sess.AgentName = "Sales Agent"
+			sess.Variables["order_id"] = "A-1"
+			sess.Messages = append(sess.Messages, openai.UserMessage("hi"), openai.AssistantMessage("hello"))
+			if err := store.Save(ctx, sess); err != nil {
+				t.Fatal(err)
+			}
+
+			first, err := store.Load(ctx, "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, _ := store.Load(ctx, "s1")
+
+			if first.Version != 1 || first.AgentName != "Sales Agent" || first.Variables["order_id"] != "A-1" {
+				t.Fatalf("unexpected session: %+v", first)
+			}
+			if len(first.Messages) != 2 || history.Text(first.Messages[1]) != "hello" {
+				t.Fatalf("unexpected messages: %+v", first.Messages)
+			}
+
+			// the second writer started from the same version and must not overwrite the first
+			first.Messages = append(first.Messages, openai.UserMessage("first"))
+			if err := store.Save(ctx, first); err != nil {
+				t.Fatal(err)
+			}
+			second.Messages = append(second.Messages, openai.UserMessage("second"))
+			if err := store.Save(ctx, second); !errors.Is(err, session.ErrConflict) {
+				t.Fatalf("expected ErrConflict, got %v", err)
+			}
+
+			if err := store.Delete(ctx, "s1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Load(ctx, "s1"); !errors.Is(err, session.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound after delete, got %v", err)
+			}
+		})
+	}
+}
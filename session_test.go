@@ -0,0 +1,115 @@
+package goswarm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/session"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestRunSession(t *testing.T) {
+	var requests []fakeRequest
+
+	salesAgent := goswarm.NewAgent(option.WithAgentName("Sales Agent"))
+	transferToSales := func(ctx goswarm.Context) *types.Agent {
+		ctx.SetVariable("department", "sales")
+		return salesAgent
+	}
+	triageAgent := goswarm.NewAgent(
+		option.WithAgentName("Triage Agent"),
+		option.WithAgentFunctions(transferToSales),
+	)
+
+	registry, err := goswarm.NewRegistry(triageAgent, salesAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := session.NewMemoryStore()
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return toolCallReply("call_1", req.Tools[0]["function"].(map[string]any)["name"].(string), "{}")
+		}
+		return assistantReply("How can sales help?")
+	}, option.WithSessionStore(store), option.WithRegistry(registry), option.WithStartAgent(triageAgent))
+
+	ctx := goswarm.NewContext(context.Background())
+
+	if _, err := client.RunSession(ctx, "s1", "I want to buy bees."); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.RunSession(ctx, "s1", "Ten please.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Agent != salesAgent {
+		t.Fatalf("expected the session to continue with the sales agent, got %s", resp.Agent.Name)
+	}
+
+	// system, first user message, tool call, tool result, answer, second user message
+	if n := len(requests[2].Messages); n != 6 {
+		t.Fatalf("expected the stored history to be sent, got %d messages", n)
+	}
+
+	sess, err := store.Load(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.AgentName != "Sales Agent" || sess.Variables["department"] != "sales" || len(sess.Messages) != 6 {
+		t.Fatalf("unexpected session: %+v", sess)
+	}
+}
+
+func TestContinueSession(t *testing.T) {
+	var requests []fakeRequest
+	refunded = nil
+
+	agent := goswarm.NewAgent(
+		option.WithAgentName("Agent"),
+		option.WithAgentFunctions(ProcessRefund),
+		option.WithAgentApprovalRequired(ProcessRefund),
+	)
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		if len(requests) > 1 {
+			return assistantReply("done")
+		}
+		return toolCallReply("call_refund", req.Tools[0]["function"].(map[string]any)["name"].(string), `{"OrderID":"A-1"}`)
+	}, option.WithSessionStore(session.NewMemoryStore()), option.WithStartAgent(agent))
+
+	ctx := goswarm.NewContext(context.Background())
+
+	resp, err := client.RunSession(ctx, "s1", "Refund A-1.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.PendingApprovals) != 1 {
+		t.Fatalf("expected the refund to wait for approval, got %+v", resp.PendingApprovals)
+	}
+
+	if _, err := client.RunSession(ctx, "s1", "Well?"); err == nil {
+		t.Fatal("expected a paused session to refuse a new message")
+	}
+
+	resp, err = client.ContinueSession(ctx, "s1", []types.ApprovalDecision{goswarm.Approve("call_refund")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunded) != 1 || len(resp.PendingApprovals) != 0 {
+		t.Fatalf("expected the approved refund, got refunds %v and %+v", refunded, resp.PendingApprovals)
+	}
+
+	if _, err := client.ContinueSession(ctx, "s1", nil); !errors.Is(err, goswarm.ErrNoPendingToolCalls) {
+		t.Fatalf("expected ErrNoPendingToolCalls, got %v", err)
+	}
+	if _, err := client.RunSession(ctx, "s1", "Thanks."); err != nil {
+		t.Fatal(err)
+	}
+}
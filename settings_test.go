@@ -0,0 +1,56 @@
+package goswarm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/option"
+)
+
+func TestModelSettings(t *testing.T) {
+	var requests []fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		requests = append(requests, req)
+		return assistantReply("Done.")
+	})
+
+	extractor := goswarm.NewAgent(
+		option.WithAgentTemperature(0),
+		option.WithAgentSeed(42),
+		option.WithAgentStop("END"),
+		option.WithAgentUser("tenant-1"),
+	)
+	messages := goswarm.NewMessages(openai.UserMessage("Extract the order ID."))
+
+	client.Run(goswarm.NewContext(context.Background()), extractor, messages)
+	client.Run(goswarm.NewContext(context.Background()), extractor, messages,
+		option.WithTemperature(0.7),
+		option.WithMaxTokens(100),
+		option.WithMetadata(map[string]string{"request": "r-1"}),
+	)
+	client.Run(goswarm.NewContext(context.Background()), goswarm.NewAgent(), messages)
+
+	agentReq := requests[0]
+	if agentReq.Temperature == nil || *agentReq.Temperature != 0 || agentReq.Seed == nil || *agentReq.Seed != 42 {
+		t.Fatalf("expected the agent settings, got %+v", agentReq)
+	}
+	if len(agentReq.Stop) != 1 || agentReq.Stop[0] != "END" || agentReq.User != "tenant-1" || agentReq.MaxCompletionTokens != nil {
+		t.Fatalf("unexpected agent settings: %+v", agentReq)
+	}
+
+	runReq := requests[1]
+	if *runReq.Temperature != 0.7 || *runReq.Seed != 42 || *runReq.MaxCompletionTokens != 100 || runReq.Metadata["request"] != "r-1" {
+		t.Fatalf("expected the run settings to override the agent settings, got %+v", runReq)
+	}
+
+	if defaults := requests[2]; defaults.Temperature != nil || defaults.Seed != nil || defaults.Stop != nil {
+		t.Fatalf("expected no settings to be sent, got %+v", defaults)
+	}
+	if extractor.ModelSettings.MaxTokens != nil {
+		t.Fatal("expected the run settings to leave the agent unchanged")
+	}
+}
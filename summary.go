@@ -0,0 +1,121 @@
+package goswarm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/tokenizer"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+const summaryPrompt = `Summarise the conversation below so that it can replace the original messages.
+Keep names, identifiers, decisions, open questions and every fact related to these context variables: %s
+
+Previous summary:
+%s
+
+Conversation:
+%s`
+
+// summarizeHistory returns the history as the model should see it: the turns covered by the
+// running summary are replaced with a single summary message. When the result is still above
+// the threshold, the summary is extended with the next older turns by running the summariser agent.
+// The summary is kept in the state of the run.
+func (s *Swarm) summarizeHistory(ctx Context, cfg *types.Summarization, msgs []openai.ChatCompletionMessageParamUnion, model string, debug bool) []openai.ChatCompletionMessageParamUnion {
+	counter := history.Counter(cfg.Counter)
+	if counter == nil {
+		var err error
+		if counter, err = tokenizer.CounterForModel(model); err != nil {
+			debugPrint(debug, "Estimating tokens with four characters per token: %v", err)
+			counter = history.EstimateTokens
+		}
+	}
+
+	// Covered counts messages of the run history, msgs is the view of the active agent
+	start, offset := historyWindow(ctx)
+
+	var state types.HistorySummary
+	scope := getRunScope(ctx)
+	if scope != nil {
+		state = scope.state.summary
+	}
+	covered := 0
+	if state.Covered > 0 {
+		covered = state.Covered - offset
+	}
+	if covered > len(msgs) || (covered > 0 && covered < start) {
+		// the history does not belong to this summary, or a handoff filter replaced what it covers
+		state, covered = types.HistorySummary{}, 0
+	}
+
+	view := summaryView(state.Text, covered, msgs)
+
+	tokens := 0
+	for _, msg := range view {
+		tokens += counter(msg)
+	}
+	if float64(tokens) <= cfg.Threshold*float64(cfg.ContextWindow) || cfg.Agent == nil {
+		return view
+	}
+
+	rest := msgs[covered:]
+	kept := history.KeepLastTurns(max(cfg.KeepTurns, 1)).Apply(rest)
+	cut := covered + len(rest) - len(kept)
+	if cut < start {
+		// the filtered history has no place in the run history, it is summarised as a whole
+		cut = start
+	}
+	if cut <= covered || cut >= len(msgs) {
+		return view
+	}
+
+	varsJSON, _ := json.Marshal(ctx.GetVariables())
+
+	prompt := fmt.Sprintf(summaryPrompt, varsJSON, state.Text, history.Transcript(msgs[covered:cut]))
+	// the summariser runs on a copy of the context, the caller keeps changing ctx for the request
+	resp := s.Run(NewContext(ctx.GetContext()), cfg.Agent, NewMessages(openai.UserMessage(prompt)),
+		option.WithModel(cfg.Agent.Model),
+		option.WithMaxTurns(1),
+		option.WithExecuteTools(false),
+		option.WithDebug(debug),
+	)
+	if len(resp.Messages) == 0 {
+		debugPrint(debug, "Summarization failed, sending the history unchanged.")
+		return view
+	}
+
+	state = types.HistorySummary{
+		Text:    history.Text(resp.Messages[len(resp.Messages)-1]),
+		Covered: cut + offset,
+	}
+	if scope != nil {
+		scope.state.summary = state
+	}
+	debugPrint(debug, "Summarized %d messages.", cut)
+
+	return summaryView(state.Text, cut, msgs)
+}
+
+// historyWindow relates the view of the active agent to the run history: view messages from
+// start on are history messages at their index plus offset. Before start the view holds what a
+// handoff filter made of the earlier history.
+func historyWindow(ctx Context) (start, offset int) {
+	scope := getRunScope(ctx)
+	if scope == nil || scope.state.filtered == nil {
+		return 0, 0
+	}
+	return len(scope.state.filtered), scope.state.filterEnd - len(scope.state.filtered)
+}
+
+func summaryView(text string, covered int, msgs []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	if covered == 0 {
+		return msgs
+	}
+
+	view := []openai.ChatCompletionMessageParamUnion{history.NewSummary(text)}
+	return append(view, msgs[covered:]...)
+}
@@ -0,0 +1,138 @@
+package goswarm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+func TestSummarization(t *testing.T) {
+	var prompts []string
+	var lastRequest fakeRequest
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if messageText(req.Messages[0]) == "Summarise." {
+			prompts = append(prompts, messageText(req.Messages[1]))
+			return assistantReply("SUMMARY")
+		}
+		lastRequest = req
+		return assistantReply("ok")
+	})
+
+	summariser := goswarm.NewAgent(option.WithAgentInstructions("Summarise."))
+	agent := goswarm.NewAgent(option.WithAgentSummarization(&types.Summarization{
+		Agent:         summariser,
+		ContextWindow: 100,
+		Threshold:     0.5,
+		KeepTurns:     1,
+	}))
+
+	ctx := goswarm.NewContext(context.Background())
+	ctx.SetVariables(types.ContextVariables{"order_id": "A-1"})
+
+	long := strings.Repeat("lorem ipsum ", 10)
+	messages := goswarm.NewMessages(nil)
+	for i := 0; i < 3; i++ {
+		messages = append(messages, openai.UserMessage("question "+long), openai.AssistantMessage("answer "+long))
+	}
+	messages = append(messages, openai.UserMessage("latest question"))
+
+	resp := client.Run(ctx, agent, messages)
+
+	if len(prompts) != 1 {
+		t.Fatalf("expected one summariser call, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[0], `"order_id":"A-1"`) {
+		t.Errorf("summariser prompt does not mention the context variables: %s", prompts[0])
+	}
+	if len(lastRequest.Messages) != 3 || lastRequest.Messages[1]["name"] != "history_summary" {
+		t.Fatalf("expected system, summary and latest question, got %+v", lastRequest.Messages)
+	}
+
+	if resp.Summary.Covered != 6 {
+		t.Fatalf("expected 6 covered messages, got %d", resp.Summary.Covered)
+	}
+	if _, ok := ctx.GetVariables()["history_summary"]; ok {
+		t.Fatal("the summary leaked into the context variables")
+	}
+
+	// the next run only summarises the turns that were not covered yet
+	messages = append(messages, openai.AssistantMessage("answer "+long), openai.UserMessage("question "+long),
+		openai.AssistantMessage("answer "+long), openai.UserMessage("final question"))
+	client.Run(ctx, agent, messages, option.WithHistorySummary(resp.Summary))
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected two summariser calls, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "Previous summary:\nSUMMARY") || strings.Count(prompts[1], "question lorem") != 1 {
+		t.Errorf("summary was not extended incrementally: %s", prompts[1])
+	}
+}
+
+func TestSummarizationAfterHandoffFilter(t *testing.T) {
+	var salesRequest fakeRequest
+	salesTurns := 0
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		switch messageText(req.Messages[0]) {
+		case "Summarise.":
+			return assistantReply("SUMMARY")
+		case "Triage.":
+			return toolCallReply("call_1", "transfer_to_sales_agent", `{}`)
+		}
+		salesRequest = req
+		if salesTurns++; salesTurns == 1 {
+			return toolCallReply("call_2", "github_com/chiwooi/go-swarm_test_lookupOrder", `{}`)
+		}
+		return assistantReply("Buy bees!")
+	})
+
+	summariser := goswarm.NewAgent(option.WithAgentInstructions("Summarise."))
+	sales := goswarm.NewAgent(
+		option.WithAgentName("Sales Agent"),
+		option.WithAgentInstructions("Sell."),
+		option.WithAgentFunctions(lookupOrder),
+		option.WithAgentSummarization(&types.Summarization{
+			Agent:         summariser,
+			ContextWindow: 100,
+			Threshold:     0.5,
+			KeepTurns:     1,
+		}),
+	)
+	triage := goswarm.NewAgent(
+		option.WithAgentInstructions("Triage."),
+		option.WithAgentHandoffs(goswarm.NewHandoff(sales, option.WithHandoffInputFilter(history.StripToolCalls()))),
+	)
+
+	long := strings.Repeat("lorem ipsum ", 10)
+	messages := goswarm.NewMessages(nil)
+	for i := 0; i < 3; i++ {
+		messages = append(messages, openai.UserMessage("question "+long), openai.AssistantMessage("answer "+long))
+	}
+	messages = append(messages, openai.UserMessage("latest question"))
+
+	ctx := goswarm.NewContext(context.Background())
+	resp := client.Run(ctx, triage, messages)
+	if resp.Agent != sales {
+		t.Fatalf("expected a handoff to sales, got %s", resp.Agent.Name)
+	}
+
+	// the filter hid the transfer, the summary stands in for everything the filter kept
+	full := append(messages, resp.Messages...)
+	state := resp.Summary
+	if state.Covered != 9 || history.Text(full[state.Covered]) != "" || len(history.ToolCalls(full[state.Covered])) != 1 {
+		t.Fatalf("expected the summary to cover the history up to the lookup call, got %d", state.Covered)
+	}
+
+	// system prompt, summary, lookup call and its result
+	if len(salesRequest.Messages) != 4 || salesRequest.Messages[1]["name"] != "history_summary" {
+		t.Fatalf("expected the summarised view, got %+v", salesRequest.Messages)
+	}
+}
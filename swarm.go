@@ -4,9 +4,8 @@ import (
 	"encoding/json"
 //	"errors"
 	"fmt"
-	"reflect"
-	"runtime"
 
+	"github.com/chiwooi/go-swarm/history"
 	"github.com/chiwooi/go-swarm/option"
 	"github.com/chiwooi/go-swarm/types"
 	"github.com/openai/openai-go"
@@ -17,35 +16,62 @@ var __CTX_VARS_NAME__ = "context_variables"
 
 // Swarm represents a collection of agents that interact with OpenAI's API.
 type Swarm struct {
-	client *openai.Client
+	client  *openai.Client
+	options option.SwarmOptions
 }
 
 // NewSwarm initializes a Swarm with an optional OpenAI client.
-func NewSwarm(client *openai.Client) *Swarm {
+func NewSwarm(client *openai.Client, opts ...option.SwarmOption) *Swarm {
 	if client == nil {
 		client = openai.NewClient() // Initialize a new client if none is provided
 	}
-	return &Swarm{client: client}
+
+	options := option.DefSwarmOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&options)
+	}
+
+	return &Swarm{client: client, options: options}
 }
 
 // GetChatCompletion retrieves chat completions from the OpenAI API.
 // - stream
 //   true  : *ssestream.Stream[ChatCompletionChunk]
 //   false : *openai.ChatCompletion
-func (s *Swarm) GetChatCompletion(ctx Context, agent *types.Agent, history []openai.ChatCompletionMessageParamUnion, modelOverride string, stream bool, debug bool) (any, error) {
-	var instructions string
+func (s *Swarm) GetChatCompletion(ctx Context, agent *types.Agent, history []openai.ChatCompletionMessageParamUnion, modelOverride string, stream bool, debug bool, opts ...option.RunOption) (any, error) {
+	args := option.DefRunOptions
+	for _, opt := range opts {
+		opt.ApplyOption(&args)
+	}
 
 	ctx = NewContext(ctx)
 	ctx.SetAnalyze(true)
 
-	switch v := agent.Instructions.(type) {
-	case string:
-		instructions = v
-	case func(Context) string:
-		// if reflect.TypeOf(agent.Instructions).Kind() == reflect.Func
-		instructions = v(ctx)
-	default:
-		return nil, fmt.Errorf("invalid instructions type: %T", v)
+	instructions, err := getInstructions(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	model := agent.Model
+	if modelOverride != "" {
+		model = modelOverride
+	}
+
+	// summarise and trim the history, the run options take precedence over the agent settings
+	summarization := agent.Summarization
+	if args.Summarization != nil {
+		summarization = args.Summarization
+	}
+	if summarization != nil {
+		history = s.summarizeHistory(ctx, summarization, history, model, debug)
+	}
+
+	strategy := agent.HistoryStrategy
+	if args.HistoryStrategy != nil {
+		strategy = args.HistoryStrategy
+	}
+	if strategy != nil {
+		history = strategy.Apply(history)
 	}
 
 	var messages []openai.ChatCompletionMessageParamUnion
@@ -53,21 +79,24 @@ func (s *Swarm) GetChatCompletion(ctx Context, agent *types.Agent, history []ope
 	messages = append(messages, openai.SystemMessage(instructions))
 	messages = append(messages, history...)
 
+	if vault := getVault(ctx); vault != nil {
+		for i, msg := range messages {
+			messages[i] = vault.RedactMessage(msg)
+		}
+	}
+
 	if debug {
 		fmt.Printf("Getting chat completion for: \n%+v\n", messages)
 	}
 
-	tools := make([]openai.ChatCompletionToolParam, len(agent.Functions))
-	for i, f := range agent.Functions {
+	functions := s.enabledFunctions(ctx, agent)
+	offerFunctions(ctx, functions)
+	tools := make([]openai.ChatCompletionToolParam, len(functions))
+	for i, f := range functions {
 		tools[i], _ = functionToJSON(ctx, f) // Assuming FunctionToJSON is defined to convert functions to JSON
 	}
 
 	// Prepare the chat completion request
-	model := agent.Model
-	if modelOverride != "" {
-		model = modelOverride
-	}
-
 	createParams := openai.ChatCompletionNewParams{
 		Model:             openai.F(model),
 		Messages:          openai.F(messages),
@@ -80,6 +109,12 @@ func (s *Swarm) GetChatCompletion(ctx Context, agent *types.Agent, history []ope
 		createParams.ParallelToolCalls = openai.F(agent.ParallelToolCalls)
 	}
 
+	applyModelSettings(&createParams, agent.ModelSettings.Merge(args.ModelSettings))
+
+	if agent.OutputType != nil {
+		createParams.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](outputFormat(agent.OutputType))
+	}
+
 	if stream {
 		streamOpt := openai.ChatCompletionStreamOptionsParam{
 			IncludeUsage: openai.F(stream),
@@ -96,6 +131,21 @@ func (s *Swarm) GetChatCompletion(ctx Context, agent *types.Agent, history []ope
 	return s.client.Chat.Completions.New(ctx.GetContext(), createParams)
 }
 
+// getInstructions resolves the instructions of the agent into the system prompt.
+func getInstructions(ctx Context, agent *types.Agent) (string, error) {
+	switch v := agent.Instructions.(type) {
+	case string:
+		return v, nil
+	case func(Context) string:
+		// if reflect.TypeOf(agent.Instructions).Kind() == reflect.Func
+		return v(ctx), nil
+	case *types.InstructionsTemplate:
+		return executeInstructions(ctx, agent, v)
+	default:
+		return "", fmt.Errorf("invalid instructions type: %T", v)
+	}
+}
+
 // HandleFunctionResult processes the result of a function call.
 func (s *Swarm) HandleFunctionResult(result interface{}, debug bool) types.Result {
 	switch res := result.(type) {
@@ -131,18 +181,24 @@ func (s *Swarm) HandleToolCalls(ctx Context, toolCalls []openai.ChatCompletionMe
 
 	functionMap := make(map[string]types.AgentFunction)
 	for _, f := range functions {
-		fnVal := reflect.ValueOf(f)
-		fnName := runtime.FuncForPC(fnVal.Pointer()).Name()
-		fnName = funcNameNormalization(fnName)
-		functionMap[fnName] = f
+		functionMap[functionName(f)] = f
 	}
 
 	partialResponse := types.Response{
 		Messages:         []openai.ChatCompletionMessageParamUnion{},
 	}
 
+	var attachments []openai.ChatCompletionMessageParamUnion
+
 	for _, toolCall := range toolCalls {
 		name := toolCall.Function.Name
+		if !isOffered(ctx, name) {
+			debugPrint(debug, "Tool %s is disabled for this turn.", name)
+			partialResponse.Messages = append(partialResponse.Messages,
+				openai.ToolMessage(toolCall.ID, fmt.Sprintf("Error: Tool %s is not available right now.", name)),
+			)
+			continue
+		}
 		if _, found := functionMap[name]; !found {
 			if debug {
 				fmt.Printf("Tool %s not found in function map.\n", name)
@@ -167,15 +223,33 @@ func (s *Swarm) HandleToolCalls(ctx Context, toolCalls []openai.ChatCompletionMe
 			fmt.Printf("Calling function %s with args: %+v\n", name, args)
 		}
 
-		rawResult := callFuncByArgs(ctx, functionMap[name], args)
+		var rawResult any
+		switch tool := functionMap[name].(type) {
+		case *types.AgentTool:
+			rawResult = s.callAgentTool(ctx, tool, args)
+		case *types.Handoff:
+			rawResult = callHandoff(tool, toolCall.Function.Arguments)
+		case *artifactReader:
+			rawResult = s.readArtifact(ctx, args)
+		default:
+			rawResult = callFuncByArgs(ctx, tool, args)
+		}
 
 		result := s.HandleFunctionResult(rawResult, debug)
 		partialResponse.Messages = append(partialResponse.Messages, openai.ToolMessage(toolCall.ID, result.Value))
+		if len(result.Attachments) > 0 {
+			// tool messages only carry text, the attachments follow the tool results
+			text := fmt.Sprintf("Attachments returned by the tool call %s (%s):", toolCall.ID, name)
+			message := UserMessage(text, result.Attachments...)
+			message.Name = openai.F(history.AttachmentsName)
+			attachments = append(attachments, message)
+		}
 
 		if result.Agent != nil {
 			partialResponse.Agent = result.Agent
 		}
 	}
+	partialResponse.Messages = append(partialResponse.Messages, attachments...)
 
 	return partialResponse
 }
@@ -187,80 +261,150 @@ func (s *Swarm) RunAndStream(ctx Context, agent *types.Agent, messages []openai.
 		opt.ApplyOption(&args)
 	}
 
+	// make sure every step of the run shares the same variables
+	ctx.GetVariables()
+	ctx = s.withRedaction(ctx)
+
+	state := &runState{
+		agent:   agent,
+		history: messages,
+		initLen: len(messages),
+		summary: args.HistorySummary,
+	}
+
+	return s.runStream(ctx, state, args, opts)
+}
+
+// runStream drives the agent loop from the given state and streams the chunks of every completion.
+func (s *Swarm) runStream(ctx Context, state *runState, args option.RunOptions, opts []option.RunOption) <-chan any {
 	responseChan := make(chan any)
 	go func() {
 		defer close(responseChan)
 
 		ctx = NewContext(ctx)
 		ctx.SetAnalyze(true)
+		ctx = withRunScope(ctx, state, args)
+
+		var approvals []types.PendingApproval
+		var err error
+
+		// streamed chunks cannot be taken back, so every input guardrail runs before the first call
+		if state.turns == 0 && len(state.pending) == 0 {
+			var waitInput func() error
+			waitInput, err = s.checkInput(ctx, state.agent, state.history, args)
+			if err == nil && waitInput != nil {
+				err = waitInput()
+			}
+		}
+
+		for err == nil {
+			if len(state.pending) > 0 {
+				approvals, err = s.runTools(ctx, state, args)
+				if cpErr := s.saveCheckpoint(ctx, state, args); err == nil {
+					err = cpErr
+				}
+				if err != nil {
+					break
+				}
+				if len(approvals) > 0 {
+					debugPrint(args.Debug, "Waiting for approval of %d tool calls.", len(approvals))
+					break
+				}
+			}
 
-		activeAgent := agent
-		history := messages
-		initLen := len(messages)
+			if state.used() >= args.MaxTurns {
+				break
+			}
 
-		for len(history)-initLen < args.MaxTurns {
-			completionRaw, err := s.GetChatCompletion(ctx, activeAgent, history, args.Model, true, args.Debug)
+			var completionRaw any
+			completionRaw, err = s.GetChatCompletion(ctx, state.agent, state.view(), args.Model, true, args.Debug, opts...)
 			if err != nil {
 				if args.Debug {
 					fmt.Println("Error getting chat completion:", err)
 				}
-				return
+				break
 			}
 
 			stream, ok := completionRaw.(*ssestream.Stream[openai.ChatCompletionChunk])
 			if !ok {
-				if args.Debug {
-					fmt.Println("Error casting stream.")
-				}
-				return
+				err = fmt.Errorf("unexpected completion type %T", completionRaw)
+				break
 			}
 
 			acc := openai.ChatCompletionAccumulator{}
+			rehydrator := newChunkRehydrator(ctx)
+
+			// the chunks of an agent with output guardrails are held back until the guardrails passed
+			var held []any
+			send := func(chunk any) {
+				if len(state.agent.OutputGuardrails) > 0 {
+					held = append(held, chunk)
+					return
+				}
+				responseChan <- chunk
+			}
 
 			responseChan <- "start"
 
 			// Handle streaming chunks here
 			for stream.Next() {
 				chunk := stream.Current()
-				responseChan <- chunk
 				acc.AddChunk(chunk)
+				send(rehydrator.chunk(chunk))
+			}
+			if chunk, ok := rehydrator.flush(); ok {
+				send(chunk)
 			}
 
-			responseChan <- "end"
-
-			if err := stream.Err(); err != nil {
+			if err = stream.Err(); err != nil {
+				responseChan <- "end"
 				if args.Debug {
 					fmt.Println("Error in stream:", err)
 				}
-				return
+				break
 			}
-			message := acc.Choices[0].Message
+			message := rehydrate(ctx, acc.Choices[0].Message)
 			debugPrint(args.Debug, "Received completion: %+v", message)
-			history = append(history, message)
+			state.history = append(state.history, message)
+			state.turns++
 
 			if len(message.ToolCalls) == 0 {
+				if err = s.checkOutput(ctx, state.agent, message.Content, args); err != nil {
+					// the output is withheld from the caller
+					state.history = state.history[:len(state.history)-1]
+					held = nil
+				}
+			}
+			for _, chunk := range held {
+				responseChan <- chunk
+			}
+			responseChan <- "end"
+
+			if len(message.ToolCalls) == 0 || !args.ExecuteTools {
 				if args.Debug {
 					fmt.Println("Ending turn.")
 				}
 				break
 			}
 
-			if args.ExecuteTools {
-				toolCalls := []openai.ChatCompletionMessageToolCall{}
-				for _, toolCall := range message.ToolCalls {
-					toolCalls = append(toolCalls, toolCall)
-				}
-				partialResponse := s.HandleToolCalls(ctx, toolCalls, activeAgent.Functions, args.Debug)
-				history = append(history, partialResponse.Messages...)
-				if partialResponse.Agent != nil {
-					activeAgent = partialResponse.Agent
-				}
-			}
+			state.pending = message.ToolCalls
+			err = s.saveCheckpoint(ctx, state, args)
+		}
+
+		// a run waiting for approval is not finished, it resumes with the decisions
+		state.done = err != nil || len(approvals) == 0
+		if cpErr := s.saveCheckpoint(ctx, state, args); err == nil {
+			err = cpErr
 		}
 
 		responseChan <- &types.Response{
-			Messages:         history[initLen:],
-			Agent:            activeAgent,
+			Messages:         state.history[state.initLen:],
+			Agent:            state.agent,
+			PendingApprovals: approvals,
+			Error:            err,
+			Handoffs:         state.handoffs,
+			Summary:          state.summary,
+			ArtifactScope:    state.artifactScope,
 		}
 	}()
 
@@ -276,6 +420,10 @@ func (s *Swarm) Run(ctx Context, agent *types.Agent, messages []openai.ChatCompl
 
 	// modelOverride string, stream bool, debug bool, maxTurns int, executeTools bool
 
+	// make sure every step of the run shares the same variables
+	ctx.GetVariables()
+	ctx = s.withRedaction(ctx)
+
 	if args.Stream {
 		responseChan := s.RunAndStream(ctx, agent, messages, opts...)
 		for response := range responseChan {
@@ -287,43 +435,144 @@ func (s *Swarm) Run(ctx Context, agent *types.Agent, messages []openai.ChatCompl
 		return NewResponse(messages, agent)
 	}
 
-	activeAgent := agent
-	history := messages
-	initLen := len(messages)
+	state := &runState{
+		agent:   agent,
+		history: messages,
+		initLen: len(messages),
+		summary: args.HistorySummary,
+	}
 
-	for len(history)-initLen < args.MaxTurns {
-		completionRaw, err := s.GetChatCompletion(ctx, activeAgent, history, args.Model, false, args.Debug)
-		if err != nil {
+	return s.runLoop(ctx, state, args, opts)
+}
+
+// runState is the state of the agent loop between two steps.
+type runState struct {
+	agent   *types.Agent
+	history []openai.ChatCompletionMessageParamUnion
+	initLen int
+	turns   int
+	// tool calls of the last assistant message that were not executed yet
+	pending []openai.ChatCompletionMessageToolCall
+	done    bool
+	// what the active agent sees of history[:filterEnd], set by a handoff with a filter
+	filtered  []openai.ChatCompletionMessageParamUnion
+	filterEnd int
+	handoffs  []types.HandoffEvent
+	pinned    bool // a violated handoff policy keeps the agent active
+	nested    int  // messages of nested runs that share the turn budget
+	// names of the functions offered with the last model call, nil before the first
+	offered []string
+	// running summary of the history, see summarizeHistory
+	summary types.HistorySummary
+	// where the tool outputs of the run are saved
+	artifactScope string
+}
+
+// used returns the part of the turn budget the run has taken.
+func (st *runState) used() int {
+	return len(st.history) - st.initLen + st.nested
+}
+
+// view returns the history as the active agent sees it.
+func (st *runState) view() []openai.ChatCompletionMessageParamUnion {
+	if st.filtered == nil {
+		return st.history
+	}
+	return append(st.filtered[:len(st.filtered):len(st.filtered)], st.history[st.filterEnd:]...)
+}
+
+// runLoop drives the agent loop from the given state until the agent stops calling tools.
+func (s *Swarm) runLoop(ctx Context, state *runState, args option.RunOptions, opts []option.RunOption) *types.Response {
+	var approvals []types.PendingApproval
+	var waitInput func() error
+	var err error
+
+	ctx = withRunScope(ctx, state, args)
+
+	if state.turns == 0 && len(state.pending) == 0 {
+		waitInput, err = s.checkInput(ctx, state.agent, state.history, args)
+	}
+
+	for err == nil {
+		if len(state.pending) > 0 {
+			approvals, err = s.runTools(ctx, state, args)
+			if cpErr := s.saveCheckpoint(ctx, state, args); err == nil {
+				err = cpErr
+			}
+			if err != nil {
+				break
+			}
+			if len(approvals) > 0 {
+				debugPrint(args.Debug, "Waiting for approval of %d tool calls.", len(approvals))
+				break
+			}
+		}
+
+		if state.used() >= args.MaxTurns {
+			break
+		}
+
+		completionRaw, completionErr := s.GetChatCompletion(ctx, state.agent, state.view(), args.Model, false, args.Debug, opts...)
+
+		// the concurrent input guardrails decide whether the first completion may be used
+		if waitInput != nil {
+			err, waitInput = waitInput(), nil
+			if err != nil {
+				break
+			}
+		}
+
+		if completionErr != nil {
 			if args.Debug {
-				fmt.Println("Error getting chat completion:", err)
+				fmt.Println("Error getting chat completion:", completionErr)
 			}
+			err = completionErr
 			break
 		}
 		completion := completionRaw.(*openai.ChatCompletion)
 
-		message := completion.Choices[0].Message
+		message := rehydrate(ctx, completion.Choices[0].Message)
 		if args.Debug {
 			fmt.Printf("Received completion: %+v\n", message)
 		}
 		// message.Sender = activeAgent.Name
-		history = append(history, message)
+		state.history = append(state.history, message)
+		state.turns++
 
 		if len(message.ToolCalls) == 0 || !args.ExecuteTools {
+			if len(message.ToolCalls) == 0 {
+				if err = s.checkOutput(ctx, state.agent, message.Content, args); err != nil {
+					// the output is withheld from the caller
+					state.history = state.history[:len(state.history)-1]
+				}
+			}
 			if args.Debug {
 				fmt.Println("Ending turn.")
 			}
 			break
 		}
 
-		partialResponse := s.HandleToolCalls(ctx, message.ToolCalls, activeAgent.Functions, args.Debug)
-		history = append(history, partialResponse.Messages...)
-		if partialResponse.Agent != nil {
-			activeAgent = partialResponse.Agent
-		}
+		state.pending = message.ToolCalls
+		err = s.saveCheckpoint(ctx, state, args)
+	}
+
+	if waitInput != nil && err == nil {
+		err = waitInput()
+	}
+
+	// a run waiting for approval is not finished, it resumes with the decisions
+	state.done = err != nil || len(approvals) == 0
+	if cpErr := s.saveCheckpoint(ctx, state, args); err == nil {
+		err = cpErr
 	}
 
 	return &types.Response{
-		Messages:         history[initLen:],
-		Agent:            activeAgent,
+		Messages:         state.history[state.initLen:],
+		Agent:            state.agent,
+		PendingApprovals: approvals,
+		Error:            err,
+		Handoffs:         state.handoffs,
+		Summary:          state.summary,
+		ArtifactScope:    state.artifactScope,
 	}
 }
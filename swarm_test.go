@@ -2,8 +2,12 @@ package goswarm_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/openai/openai-go"
+	oaioption "github.com/openai/openai-go/option"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/chiwooi/go-swarm"
@@ -43,3 +47,85 @@ func TestSwarm_GetChatCompletion(t *testing.T) {
 		fmt.Println(resp.Messages[0].(openai.ChatCompletionMessage).Content)
 	}
 }
+
+// fakeRequest is the chat completion request received by the fake server.
+type fakeRequest struct {
+	Model    string           `json:"model"`
+	Messages []map[string]any `json:"messages"`
+	Tools    []map[string]any `json:"tools"`
+
+	ResponseFormat map[string]any `json:"response_format"`
+
+	Temperature         *float64          `json:"temperature"`
+	Seed                *int64            `json:"seed"`
+	MaxCompletionTokens *int64            `json:"max_completion_tokens"`
+	Stop                []string          `json:"stop"`
+	User                string            `json:"user"`
+	Metadata            map[string]string `json:"metadata"`
+}
+
+// newFakeSwarm creates a Swarm backed by a local server that answers every chat completion
+// request with the assistant message returned by reply.
+func newFakeSwarm(t *testing.T, reply func(req fakeRequest) map[string]any, opts ...option.SwarmOption) *goswarm.Swarm {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fakeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": 0,
+			"model":   req.Model,
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       reply(req),
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	oai := openai.NewClient(
+		oaioption.WithBaseURL(srv.URL+"/"),
+		oaioption.WithAPIKey("test"),
+		oaioption.WithMaxRetries(0),
+	)
+	return goswarm.NewSwarm(oai, opts...)
+}
+
+func assistantReply(content string) map[string]any {
+	return map[string]any{"role": "assistant", "content": content}
+}
+
+func toolCallReply(id, name, args string) map[string]any {
+	return map[string]any{
+		"role": "assistant",
+		"tool_calls": []any{map[string]any{
+			"id":       id,
+			"type":     "function",
+			"function": map[string]any{"name": name, "arguments": args},
+		}},
+	}
+}
+
+// messageText returns the text of a message in the request, whether the content is a string or a list of parts.
+func messageText(msg map[string]any) string {
+	switch v := msg["content"].(type) {
+	case string:
+		return v
+	case []any:
+		text := ""
+		for _, part := range v {
+			if p, ok := part.(map[string]any); ok {
+				if t, ok := p["text"].(string); ok {
+					text += t
+				}
+			}
+		}
+		return text
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/history"
+)
+
+// Overheads added by the chat format, see the OpenAI cookbook "How to count tokens with tiktoken".
+const (
+	tokensPerMessage  = 3 // <|start|>{role}\n ... <|end|>\n
+	tokensPerName     = 1
+	tokensPerReply    = 3 // every reply is primed with <|start|>assistant<|message|>
+	tokensPerToolCall = 3
+
+	propInit = 3
+	propKey  = 3
+	enumInit = -3
+	enumItem = 3
+	funcEnd  = 12
+)
+
+// CountMessage returns the number of tokens the message occupies in the prompt, including the
+// per-message overhead of the chat format. Images and audio are estimated with history.MediaTokens.
+func (e *Encoding) CountMessage(msg openai.ChatCompletionMessageParamUnion) int {
+	count := tokensPerMessage + e.Count(history.Role(msg)) + e.Count(history.Text(msg))
+
+	if name := messageName(msg); name != "" {
+		count += tokensPerName + e.Count(name)
+	}
+
+	for _, call := range history.ToolCalls(msg) {
+		count += tokensPerToolCall + e.Count(call.Function.Name) + e.Count(call.Function.Arguments)
+	}
+
+	for _, part := range history.Media(msg) {
+		count += history.MediaTokens(part)
+	}
+
+	return count
+}
+
+// CountMessages returns the number of prompt tokens of the messages, including the tokens that
+// prime the assistant reply.
+func (e *Encoding) CountMessages(msgs []openai.ChatCompletionMessageParamUnion) int {
+	count := tokensPerReply
+	for _, msg := range msgs {
+		count += e.CountMessage(msg)
+	}
+	return count
+}
+
+// CountInstructions returns the number of tokens of the system message built from the instructions.
+func (e *Encoding) CountInstructions(instructions string) int {
+	return e.CountMessage(openai.SystemMessage(instructions))
+}
+
+// CountTools returns the number of tokens the tool definitions add to the prompt.
+func (e *Encoding) CountTools(tools []openai.ChatCompletionToolParam) int {
+	if len(tools) == 0 {
+		return 0
+	}
+
+	funcInit := 7
+	if e.name == Cl100kBase {
+		funcInit = 10
+	}
+
+	count := 0
+	for _, tool := range tools {
+		fn := tool.Function.Value
+
+		count += funcInit
+		count += e.Count(fn.Name.Value + ":" + strings.TrimSuffix(fn.Description.Value, "."))
+
+		properties := schemaProperties(fn.Parameters.Value)
+		if len(properties) == 0 {
+			continue
+		}
+
+		count += propInit
+		for name, prop := range properties {
+			count += propKey
+
+			if enum, ok := prop["enum"].([]any); ok {
+				count += enumInit
+				for _, item := range enum {
+					count += enumItem + e.Count(toString(item))
+				}
+			}
+
+			desc := strings.TrimSuffix(toString(prop["description"]), ".")
+			count += e.Count(name + ":" + toString(prop["type"]) + ":" + desc)
+		}
+	}
+
+	return count + funcEnd
+}
+
+// CounterForModel returns a history.Counter for the model. The error wraps ErrVocabularyMissing
+// when the vocabulary of the model is not embedded, callers decide whether an estimate will do.
+func CounterForModel(model string) (history.Counter, error) {
+	enc, err := EncodingForModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return enc.CountMessage, nil
+}
+
+func messageName(msg openai.ChatCompletionMessageParamUnion) string {
+	switch v := msg.(type) {
+	case openai.ChatCompletionSystemMessageParam:
+		return v.Name.Value
+	case openai.ChatCompletionUserMessageParam:
+		return v.Name.Value
+	case openai.ChatCompletionAssistantMessageParam:
+		return v.Name.Value
+	case openai.ChatCompletionFunctionMessageParam:
+		return v.Name.Value
+	case openai.ChatCompletionMessageParam:
+		return v.Name.Value
+	}
+	return ""
+}
+
+// schemaProperties returns the properties of a JSON schema object, whatever Go types it was built from.
+func schemaProperties(schema openai.FunctionParameters) map[string]map[string]any {
+	data, err := json.Marshal(schema["properties"])
+	if err != nil {
+		return nil
+	}
+
+	var properties map[string]map[string]any
+	json.Unmarshal(data, &properties)
+	return properties
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil:
+		return ""
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
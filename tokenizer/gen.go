@@ -0,0 +1,45 @@
+//go:build ignore
+
+// Downloads the BPE vocabularies embedded by the tokenizer package.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var vocabularies = map[string]string{
+	"cl100k_base": "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	"o200k_base":  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+func main() {
+	for name, hash := range vocabularies {
+		url := fmt.Sprintf("https://openaipublic.blob.core.windows.net/encodings/%s.tiktoken", name)
+
+		resp, err := http.Get(url)
+		if err != nil {
+			panic(err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			panic(err)
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != hash {
+			panic(fmt.Sprintf("checksum mismatch for %s", name))
+		}
+
+		if err := os.WriteFile(filepath.Join("vocab", name+".tiktoken"), data, 0o644); err != nil {
+			panic(err)
+		}
+		fmt.Printf("downloaded %s\n", name)
+	}
+}
@@ -0,0 +1,212 @@
+// Package tokenizer counts tokens offline with the BPE vocabularies used by the OpenAI chat models.
+package tokenizer
+
+//go:generate go run gen.go
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/regexp2"
+)
+
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// ErrVocabularyMissing is returned when the vocabulary of an encoding is not embedded.
+// Run `go generate ./tokenizer` to download the vocabularies.
+var ErrVocabularyMissing = errors.New("tokenizer: vocabulary is not embedded")
+
+//go:embed vocab
+var vocabFS embed.FS
+
+var patterns = map[string]string{
+	Cl100kBase: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+	O200kBase: strings.Join([]string{
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+		`\s*[\r\n]+`,
+		`\s+(?!\S)`,
+		`\s+`,
+	}, "|"),
+}
+
+// Encoding is a byte pair encoding.
+type Encoding struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp2.Regexp
+}
+
+// NewEncoding creates an encoding from merge ranks and a pre-tokenization pattern.
+func NewEncoding(name string, ranks map[string]int, pattern string) (*Encoding, error) {
+	re, err := regexp2.Compile(pattern, regexp2.None)
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: invalid pattern for %s: %w", name, err)
+	}
+	return &Encoding{name: name, ranks: ranks, pattern: re}, nil
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*Encoding{}
+)
+
+// GetEncoding returns the embedded encoding with the given name (cl100k_base or o200k_base).
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+
+	pattern, ok := patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("tokenizer: unknown encoding %s", name)
+	}
+
+	data, err := vocabFS.ReadFile("vocab/" + name + ".tiktoken")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrVocabularyMissing, name)
+	}
+
+	ranks, err := parseRanks(data)
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: invalid vocabulary %s: %w", name, err)
+	}
+
+	enc, err := NewEncoding(name, ranks, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	encodings[name] = enc
+	return enc, nil
+}
+
+// EncodingNameForModel returns the name of the encoding used by the model.
+// Unknown models are assumed to use o200k_base like the current chat models.
+func EncodingNameForModel(model string) string {
+	model = strings.TrimPrefix(model, "ft:")
+
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4."):
+		return O200kBase
+	case strings.HasPrefix(model, "gpt-4"), strings.HasPrefix(model, "gpt-3.5"), strings.HasPrefix(model, "gpt-35"),
+		strings.HasPrefix(model, "text-embedding-"):
+		return Cl100kBase
+	}
+	return O200kBase
+}
+
+// EncodingForModel returns the encoding used by the model.
+func EncodingForModel(model string) (*Encoding, error) {
+	return GetEncoding(EncodingNameForModel(model))
+}
+
+// parseRanks reads a vocabulary in the tiktoken format: one base64 encoded token and its rank per line.
+func parseRanks(data []byte) (map[string]int, error) {
+	ranks := map[string]int{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(token)] = rank
+	}
+
+	return ranks, scanner.Err()
+}
+
+// Name returns the name of the encoding.
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode converts text into tokens. Special tokens are encoded as ordinary text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+
+	m, _ := e.pattern.FindStringMatch(text)
+	for m != nil {
+		piece := m.String()
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+		} else {
+			tokens = append(tokens, e.bytePairEncode([]byte(piece))...)
+		}
+		m, _ = e.pattern.FindNextMatch(m)
+	}
+
+	return tokens
+}
+
+// Count returns the number of tokens in text.
+func (e *Encoding) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(e.Encode(text))
+}
+
+// bytePairEncode repeatedly merges the adjacent parts with the lowest rank until no merge is possible.
+func (e *Encoding) bytePairEncode(piece []byte) []int {
+	// boundaries of the parts, parts[i] is piece[bounds[i]:bounds[i+1]]
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := e.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+
+	tokens := make([]int, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		rank, ok := e.ranks[string(piece[bounds[i]:bounds[i+1]])]
+		if !ok {
+			// every single byte is part of a complete vocabulary
+			rank = -1
+		}
+		tokens = append(tokens, rank)
+	}
+	return tokens
+}
@@ -0,0 +1,104 @@
+package tokenizer_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/tokenizer"
+)
+
+func testEncoding(t *testing.T) *tokenizer.Encoding {
+	t.Helper()
+
+	ranks := map[string]int{}
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	for i, merge := range []string{"he", "ll", "llo", "hell"} {
+		ranks[merge] = 256 + i
+	}
+
+	enc, err := tokenizer.NewEncoding("test", ranks, `\s+(?!\S)|\s+|\S+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func TestEncodeMergesByRank(t *testing.T) {
+	tokens := testEncoding(t).Encode("hello")
+
+	// "llo" has a lower rank than "hell", so "hello" splits into "he" + "llo"
+	if len(tokens) != 2 || tokens[0] != 256 || tokens[1] != 258 {
+		t.Fatalf("unexpected tokens: %v", tokens)
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	enc := testEncoding(t)
+
+	msgs := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("hello"),
+		openai.UserMessage("hello"),
+	}
+
+	// 3 tokens of overhead per message, the role, the content and 3 tokens priming the reply
+	want := 2*3 + enc.Count("system") + enc.Count("user") + 2*enc.Count("hello") + 3
+	if got := enc.CountMessages(msgs); got != want {
+		t.Fatalf("expected %d tokens, got %d", want, got)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]string{
+		"gpt-4o":         tokenizer.O200kBase,
+		"gpt-4o-mini":    tokenizer.O200kBase,
+		"gpt-4-turbo":    tokenizer.Cl100kBase,
+		"gpt-3.5-turbo":  tokenizer.Cl100kBase,
+		"ft:gpt-4o:acme": tokenizer.O200kBase,
+	}
+	for model, want := range cases {
+		if got := tokenizer.EncodingNameForModel(model); got != want {
+			t.Errorf("%s: expected %s, got %s", model, want, got)
+		}
+	}
+}
+
+// vocabularyHashes are the checksums gen.go verifies the downloads with.
+var vocabularyHashes = map[string]string{
+	tokenizer.Cl100kBase: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	tokenizer.O200kBase:  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+func TestEmbeddedVocabulary(t *testing.T) {
+	for name, hash := range vocabularyHashes {
+		data, err := os.ReadFile(filepath.Join("vocab", name+".tiktoken"))
+		if err != nil {
+			t.Fatalf("%s is not committed, run go generate ./tokenizer: %v", name, err)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+			t.Fatalf("%s does not match the checksum of the published vocabulary", name)
+		}
+
+		enc, err := tokenizer.GetEncoding(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := enc.Count("hello world"); n != 2 {
+			t.Errorf("%s: expected 2 tokens, got %d", name, n)
+		}
+
+		counter, err := tokenizer.CounterForModel(map[string]string{tokenizer.Cl100kBase: "gpt-4", tokenizer.O200kBase: "gpt-4o"}[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := counter(openai.UserMessage("hello world")); n != 3+1+2 {
+			t.Errorf("%s: expected 6 tokens for the message, got %d", name, n)
+		}
+	}
+}
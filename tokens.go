@@ -0,0 +1,39 @@
+package goswarm
+
+import (
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm/tokenizer"
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// CountTokens returns the number of prompt tokens a chat completion request for the agent uses:
+// the instructions, the history and the tool schemas of the enabled agent functions.
+// The model of the agent is used when model is empty. The read_artifact tool, which depends on the
+// artifact store of the Swarm, is not counted.
+func CountTokens(ctx Context, agent *types.Agent, history []openai.ChatCompletionMessageParamUnion, model string) (int, error) {
+	if model == "" {
+		model = agent.Model
+	}
+
+	enc, err := tokenizer.EncodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = NewContext(ctx)
+	ctx.SetAnalyze(true)
+
+	instructions, err := getInstructions(ctx, agent)
+	if err != nil {
+		return 0, err
+	}
+
+	functions := filterFunctions(ctx, agent)
+	tools := make([]openai.ChatCompletionToolParam, len(functions))
+	for i, f := range functions {
+		tools[i], _ = functionToJSON(ctx, f)
+	}
+
+	return enc.CountInstructions(instructions) + enc.CountMessages(history) + enc.CountTools(tools), nil
+}
@@ -0,0 +1,81 @@
+package goswarm
+
+import (
+	"slices"
+
+	"github.com/chiwooi/go-swarm/types"
+)
+
+// agentFunctions returns every function the agent may call. Agents that save tool outputs as
+// artifacts also get the read_artifact tool.
+func (s *Swarm) agentFunctions(agent *types.Agent) []types.AgentFunction {
+	functions := agent.Functions
+	if s.savesArtifacts(agent) {
+		functions = append(functions[:len(functions):len(functions)], readArtifact)
+	}
+	return functions
+}
+
+// enabledFunctions returns the functions of the agent its tool predicates currently enable.
+func (s *Swarm) enabledFunctions(ctx Context, agent *types.Agent) []types.AgentFunction {
+	functions := filterFunctions(ctx, agent)
+	if s.savesArtifacts(agent) {
+		functions = append(functions[:len(functions):len(functions)], readArtifact)
+	}
+	return functions
+}
+
+// offerFunctions records the functions offered to the model for the turn of the run, the tool
+// calls of its answer are checked against them.
+func offerFunctions(ctx Context, functions []types.AgentFunction) {
+	scope := getRunScope(ctx)
+	if scope == nil {
+		return
+	}
+
+	offered := make([]string, 0, len(functions))
+	for _, f := range functions {
+		offered = append(offered, functionName(f))
+	}
+	scope.state.offered = offered
+}
+
+// isOffered tells whether the function was offered to the model in the current turn of the run.
+// Outside of a run and before the first model call every function is.
+func isOffered(ctx Context, name string) bool {
+	scope := getRunScope(ctx)
+	if scope == nil || scope.state.offered == nil {
+		return true
+	}
+	return slices.Contains(scope.state.offered, name)
+}
+
+// filterFunctions returns the functions of the agent its tool predicates currently enable.
+// A predicate of the wrong type disables its function.
+func filterFunctions(ctx Context, agent *types.Agent) []types.AgentFunction {
+	if len(agent.ToolPredicates) == 0 {
+		return agent.Functions
+	}
+
+	enabled := map[string]bool{}
+	for _, predicate := range agent.ToolPredicates {
+		name := functionName(predicate.Function)
+		ok := false
+		if f, valid := predicate.Enabled.(func(Context) bool); valid {
+			ok = f(ctx)
+		}
+		// every predicate of a function must agree
+		if prev, seen := enabled[name]; seen {
+			ok = ok && prev
+		}
+		enabled[name] = ok
+	}
+
+	var functions []types.AgentFunction
+	for _, f := range agent.Functions {
+		if ok, seen := enabled[functionName(f)]; !seen || ok {
+			functions = append(functions, f)
+		}
+	}
+	return functions
+}
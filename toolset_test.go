@@ -0,0 +1,114 @@
+package goswarm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+
+	"github.com/chiwooi/go-swarm"
+	"github.com/chiwooi/go-swarm/history"
+	"github.com/chiwooi/go-swarm/option"
+)
+
+func refundOrder(ctx goswarm.Context) string {
+	if ctx.IsAnalyze() {
+		ctx.SetDescription("Refund the order.")
+		return ""
+	}
+	return "refunded"
+}
+
+func lookupOrder(ctx goswarm.Context) string {
+	if ctx.IsAnalyze() {
+		ctx.SetDescription("Look up the order.")
+		return ""
+	}
+	ctx.SetVariable("order_id", "o-1")
+	return "found o-1"
+}
+
+func TestToolPredicates(t *testing.T) {
+	const pkg = "github_com/chiwooi/go-swarm_test_"
+	var toolNames [][]string
+	turn := 0
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		var names []string
+		for _, tool := range req.Tools {
+			names = append(names, tool["function"].(map[string]any)["name"].(string))
+		}
+		toolNames = append(toolNames, names)
+
+		switch turn++; turn {
+		case 1:
+			// the refund tool is not offered yet, the call is rejected
+			return toolCallReply("call_1", pkg+"refundOrder", `{}`)
+		case 2:
+			return toolCallReply("call_2", pkg+"lookupOrder", `{}`)
+		case 3:
+			return toolCallReply("call_3", pkg+"refundOrder", `{}`)
+		}
+		return assistantReply("Done.")
+	})
+
+	agent := goswarm.NewAgent(
+		option.WithAgentFunctions(lookupOrder, refundOrder),
+		option.WithAgentToolPredicate(refundOrder, func(ctx goswarm.Context) bool {
+			return ctx.GetVariable("order_id", nil) != nil
+		}),
+	)
+
+	resp := client.Run(goswarm.NewContext(context.Background()), agent, goswarm.NewMessages(openai.UserMessage("Refund my order.")))
+
+	if len(toolNames[0]) != 1 || len(toolNames[2]) != 2 {
+		t.Fatalf("expected the refund tool once the order is known, got %v", toolNames)
+	}
+	if !strings.Contains(history.Text(resp.Messages[1]), "not available") {
+		t.Fatalf("expected the disabled tool to be rejected, got %+v", resp.Messages[1])
+	}
+	if history.Text(resp.Messages[5]) != `"refunded"` {
+		t.Fatalf("expected the enabled tool to run, got %+v", resp.Messages[5])
+	}
+}
+
+func TestToolPredicatesPerTurn(t *testing.T) {
+	const pkg = "github_com/chiwooi/go-swarm_test_"
+	turn := 0
+
+	client := newFakeSwarm(t, func(req fakeRequest) map[string]any {
+		if turn++; turn == 1 {
+			return toolCallReply("call_1", pkg+"refundOrder", `{}`)
+		}
+		return assistantReply("Done.")
+	})
+
+	// the predicate would enable the tool by the time the call runs, the offer of the turn decides
+	evaluations := 0
+	agent := goswarm.NewAgent(
+		option.WithAgentFunctions(refundOrder),
+		option.WithAgentToolPredicate(refundOrder, func(ctx goswarm.Context) bool {
+			evaluations++
+			return evaluations > 1
+		}),
+	)
+
+	resp := client.Run(goswarm.NewContext(context.Background()), agent, goswarm.NewMessages(openai.UserMessage("Refund my order.")))
+
+	if !strings.Contains(history.Text(resp.Messages[1]), "not available") {
+		t.Fatalf("expected the call outside the offered tools to be rejected, got %+v", resp.Messages[1])
+	}
+	if evaluations != 2 {
+		t.Fatalf("expected the predicate to run once per model call, got %d evaluations", evaluations)
+	}
+}
+
+func TestToolPredicateType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a predicate of the wrong type to panic")
+		}
+	}()
+	option.WithAgentToolPredicate(refundOrder, func(ctx context.Context) bool { return true })
+}
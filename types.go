@@ -14,6 +14,39 @@ func NewAgent(opts ...option.AgentOption) *types.Agent {
 		o.ApplyOption(&options)
 	}
 
+	return agentFromOptions(options)
+}
+
+// DeriveAgent returns a copy of the agent with the options applied, e.g. the per-tenant variant
+// of a shared agent. The agent itself is never changed, options that add functions or guardrails
+// add them to the copy.
+func DeriveAgent(agent *types.Agent, opts ...option.AgentOption) *types.Agent {
+	base := agent.Clone()
+	options := option.AgentOptions{
+		Name:              base.Name,
+		Model:             base.Model,
+		Instructions:      base.Instructions,
+		Functions:         base.Functions,
+		ToolChoice:        base.ToolChoice,
+		ParallelToolCalls: base.ParallelToolCalls,
+		HistoryStrategy:   base.HistoryStrategy,
+		Summarization:     base.Summarization,
+		ApprovalRequired:  base.ApprovalRequired,
+		InputGuardrails:   base.InputGuardrails,
+		OutputGuardrails:  base.OutputGuardrails,
+		OutputType:        base.OutputType,
+		ModelSettings:     base.ModelSettings,
+		ToolPredicates:    base.ToolPredicates,
+		OutputLimits:      base.OutputLimits,
+	}
+	for _, o := range opts {
+		o.ApplyOption(&options)
+	}
+
+	return agentFromOptions(options)
+}
+
+func agentFromOptions(options option.AgentOptions) *types.Agent {
 	return &types.Agent{
 		Name:              options.Name,
 		Model:             options.Model,
@@ -21,7 +54,84 @@ func NewAgent(opts ...option.AgentOption) *types.Agent {
 		Functions:         options.Functions,
 		ToolChoice:        options.ToolChoice,
 		ParallelToolCalls: options.ParallelToolCalls,
+		HistoryStrategy:   options.HistoryStrategy,
+		Summarization:     options.Summarization,
+		ApprovalRequired:  options.ApprovalRequired,
+		InputGuardrails:   options.InputGuardrails,
+		OutputGuardrails:  options.OutputGuardrails,
+		OutputType:        options.OutputType,
+		ModelSettings:     options.ModelSettings,
+		ToolPredicates:    options.ToolPredicates,
+		OutputLimits:      options.OutputLimits,
+	}
+}
+
+// NewAgentTool exposes the agent as a tool, see option.WithAgentTools.
+func NewAgentTool(agent *types.Agent, opts ...option.AgentToolOption) *types.AgentTool {
+	options := option.DefAgentToolOptions
+	for _, o := range opts {
+		o.ApplyOption(&options)
+	}
+
+	return &types.AgentTool{
+		Agent:       agent,
+		Name:        options.Name,
+		Description: options.Description,
+		SeedHistory: options.SeedHistory,
+		MaxTurns:    options.MaxTurns,
+		ShareBudget: options.ShareBudget,
+		Variables:   options.Variables,
+	}
+}
+
+// NewHandoff creates a handoff to the agent, see option.WithAgentHandoffs.
+func NewHandoff(agent *types.Agent, opts ...option.HandoffOption) *types.Handoff {
+	options := option.DefHandoffOptions
+	for _, o := range opts {
+		o.ApplyOption(&options)
+	}
+
+	return &types.Handoff{
+		Agent:        agent,
+		Name:         options.Name,
+		Description:  options.Description,
+		SummaryAgent: options.SummaryAgent,
+		InputFilter:  options.InputFilter,
+		Payload:      options.Payload,
+	}
+}
+
+// NewStage creates a pipeline stage that runs the agent.
+func NewStage(name string, agent *types.Agent, opts ...option.StageOption) types.Stage {
+	options := option.DefStageOptions
+	for _, o := range opts {
+		o.ApplyOption(&options)
+	}
+
+	return types.Stage{
+		Name:       name,
+		Agent:      agent,
+		Input:      options.Input,
+		When:       options.When,
+		MaxRetries: options.MaxRetries,
+	}
+}
+
+// NewPipeline creates a pipeline from the stages.
+func NewPipeline(name string, stages ...types.Stage) *types.Pipeline {
+	return &types.Pipeline{
+		Name:   name,
+		Stages: stages,
+	}
+}
+
+// NewRegistry creates a Registry holding the specified agents.
+func NewRegistry(agents ...*types.Agent) (*types.Registry, error) {
+	registry := &types.Registry{}
+	if err := registry.Register(agents...); err != nil {
+		return nil, err
 	}
+	return registry, nil
 }
 
 // NewResponse creates a new Response instance.
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"fmt"
+)
+
+// ConfigError is a problem in an agent definition file, see goswarm.LoadAgents.
+type ConfigError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"time"
+)
+
+// FailurePolicy decides how a fan-out deals with failed branches.
+type FailurePolicy int
+
+const (
+	FailFast   FailurePolicy = iota // The first failed branch cancels the others and fails the fan-out
+	BestEffort                      // Failed branches are left out, the fan-out fails when all of them fail
+	Quorum                          // At least Quorum branches must succeed
+)
+
+// BranchResult is the outcome of one agent of a fan-out.
+type BranchResult struct {
+	Agent    *Agent
+	Response *Response
+	Err      error
+	Duration time.Duration
+}
+
+// Reducer combines the successful branches of a fan-out into the final output.
+type Reducer func(results []BranchResult) (string, error)
+
+// FanOutResult holds every branch and the aggregated output of a fan-out.
+type FanOutResult struct {
+	Branches []BranchResult
+	Output   string
+	Response *Response // Response of the aggregator agent, nil when a reducer was used
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Handoff makes another agent the active agent through a generated transfer_to_<name> tool.
+type Handoff struct {
+	Agent       *Agent
+	Name        string // Tool name, defaults to transfer_to_<agent name>
+	Description string
+	// Optional, replaces the history before the handoff with a summary written by this agent
+	SummaryAgent *Agent
+	// Optional, selects what the receiving agent sees of the history before the handoff
+	InputFilter HistoryStrategy
+	// Optional, struct type of the arguments the model passes along with the handoff
+	Payload reflect.Type
+}
+
+// HandoffEvent records a handoff of a run. The latest one is kept in the "handoff" context variable.
+type HandoffEvent struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Tool       string `json:"tool,omitempty"` // Empty for handoffs made by agent functions
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Payload    any    `json:"payload,omitempty"` // Value of the payload type of the handoff
+}
+
+// ToolName returns the name the model calls the tool by.
+func (h *Handoff) ToolName() string {
+	if h.Name != "" {
+		return invalidToolName.ReplaceAllString(h.Name, "_")
+	}
+	name := invalidToolName.ReplaceAllString(strings.ToLower(h.Agent.Name), "_")
+	return "transfer_to_" + strings.Trim(name, "_")
+}
+
+// HandoffAction decides what happens to a handoff that violates the HandoffPolicy.
+type HandoffAction int
+
+const (
+	StopHandoff    HandoffAction = iota // The run stops with a *HandoffError
+	PinAgent                            // The handoff is refused and the current agent stays active for the rest of the run
+	CorrectHandoff                      // The handoff is refused and a system note tells the model why
+)
+
+// HandoffPolicy limits the handoffs within one run.
+type HandoffPolicy struct {
+	MaxHandoffs int // Optional, maximum number of handoffs per run
+	// Optional, refuses a handoff back to an agent that handed off within this many handoffs, e.g. A->B->A
+	PingPongWindow int
+	// Optional, the agents each agent may hand off to, by name. Agents without an entry are not limited.
+	Allowed map[string][]string
+	Action  HandoffAction
+	Note    string // Optional, replaces the default note of CorrectHandoff
+}
+
+// HandoffError stops a run when a handoff violates the HandoffPolicy.
+type HandoffError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *HandoffError) Error() string {
+	return fmt.Sprintf("handoff from %q to %q refused: %s", e.From, e.To, e.Reason)
+}
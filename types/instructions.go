@@ -0,0 +1,30 @@
+package types
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+// InstructionsTemplate renders the instructions of an agent with text/template, see InstructionsData
+// for the available fields. It is parsed once, when the agent is built.
+type InstructionsTemplate struct {
+	Template *template.Template
+}
+
+// InstructionsData is the data an InstructionsTemplate is executed with.
+type InstructionsData struct {
+	Agent string           // Name of the agent
+	Vars  ContextVariables // Context variables of the run
+	Now   time.Time
+	Date  string // Current date as 2006-01-02
+}
+
+// Execute renders the instructions.
+func (t *InstructionsTemplate) Execute(data InstructionsData) (string, error) {
+	var sb strings.Builder
+	if err := t.Template.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
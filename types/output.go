@@ -0,0 +1,19 @@
+package types
+
+// OutputStrategy decides how a tool output above its limit is shortened.
+type OutputStrategy int
+
+const (
+	TruncateOutput  OutputStrategy = iota // Keeps the beginning and marks the cut
+	HeadTailOutput                        // Keeps the beginning and the end and marks the cut in between
+	SummarizeOutput                       // Replaces the output with a summary written by the Agent of the limit
+	ArtifactOutput                        // Saves the output to the artifact store, the model pages through it with read_artifact
+)
+
+// OutputLimit limits the output a tool of the agent adds to the history.
+type OutputLimit struct {
+	Function AgentFunction // Optional, nil limits every function of the agent without a limit of its own
+	MaxChars int
+	Strategy OutputStrategy
+	Agent    *Agent // SummarizeOutput only, the summariser
+}
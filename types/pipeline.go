@@ -0,0 +1,61 @@
+package types
+
+import (
+	"time"
+)
+
+// Stage is one step of a Pipeline.
+type Stage struct {
+	Name  string
+	Agent *Agent
+	// Optional, func(goswarm.Context, *PipelineResult) string builds the input of the stage.
+	// Defaults to the output of the previous stage that ran, or the input of the pipeline.
+	Input any
+	// Optional, func(goswarm.Context, *PipelineResult) bool, the stage is skipped when it returns false.
+	When       any
+	MaxRetries int // Number of times a failed stage is run again
+}
+
+// Pipeline runs agents one after another, each consuming the output of the previous stages.
+type Pipeline struct {
+	Name   string
+	Stages []Stage
+}
+
+// StageResult is the trace of one stage of a pipeline.
+type StageResult struct {
+	Name     string
+	Input    string
+	Output   string
+	Response *Response
+	Skipped  bool
+	Attempts int
+	Err      error
+	Duration time.Duration
+}
+
+// PipelineResult is the combined trace of the stages that ran so far.
+type PipelineResult struct {
+	Input  string // Input of the pipeline
+	Stages []StageResult
+}
+
+// Stage returns the result of the named stage, or nil when it did not run yet.
+func (r *PipelineResult) Stage(name string) *StageResult {
+	for i := range r.Stages {
+		if r.Stages[i].Name == name {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// Output returns the output of the last stage that ran, or the input of the pipeline.
+func (r *PipelineResult) Output() string {
+	for i := len(r.Stages) - 1; i >= 0; i-- {
+		if !r.Stages[i].Skipped && r.Stages[i].Err == nil {
+			return r.Stages[i].Output
+		}
+	}
+	return r.Input
+}
@@ -0,0 +1,111 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry looks up agents by name, e.g. to restore the active agent of a stored session.
+type Registry struct {
+	mu     sync.RWMutex
+	agents map[string]*Agent
+}
+
+// Register adds agents to the registry. Agent names must be set and unique, derive variants of an
+// agent under another name or keep them in a registry of their own. Either all agents are
+// registered or, when one of them is invalid, none.
+func (r *Registry) Register(agents ...*Agent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := map[string]*Agent{}
+	for _, agent := range agents {
+		if agent == nil {
+			return errors.New("nil agent")
+		}
+		if agent.Name == "" {
+			return fmt.Errorf("agent without a name")
+		}
+		prev, ok := added[agent.Name]
+		if !ok {
+			prev, ok = r.agents[agent.Name]
+		}
+		if ok && prev != agent {
+			return fmt.Errorf("agent %q is already registered", agent.Name)
+		}
+		added[agent.Name] = agent
+	}
+
+	if r.agents == nil {
+		r.agents = map[string]*Agent{}
+	}
+	for name, agent := range added {
+		r.agents[name] = agent
+	}
+
+	return nil
+}
+
+// Get returns the agent registered under name.
+func (r *Registry) Get(name string) (*Agent, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agent, ok := r.agents[name]
+	return agent, ok
+}
+
+// Names returns the sorted names of the registered agents.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.agents))
+	for name := range r.agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Agents returns the registered agents, sorted by name.
+func (r *Registry) Agents() []*Agent {
+	names := r.Names()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agents := make([]*Agent, len(names))
+	for i, name := range names {
+		agents[i] = r.agents[name]
+	}
+	return agents
+}
+
+// Validate checks that every agent the registered agents hand off to or call as a tool is
+// registered too, so that a stored session or checkpoint can always be resolved.
+func (r *Registry) Validate() error {
+	var errs []error
+	for _, agent := range r.Agents() {
+		for _, f := range agent.Functions {
+			var target *Agent
+			switch v := f.(type) {
+			case *Handoff:
+				target = v.Agent
+			case *AgentTool:
+				target = v.Agent
+			default:
+				continue
+			}
+
+			if registered, ok := r.Get(target.Name); !ok {
+				errs = append(errs, fmt.Errorf("agent %q refers to the unregistered agent %q", agent.Name, target.Name))
+			} else if registered != target {
+				errs = append(errs, fmt.Errorf("agent %q refers to another agent named %q", agent.Name, target.Name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
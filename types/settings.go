@@ -0,0 +1,78 @@
+package types
+
+import (
+	"maps"
+	"slices"
+)
+
+// ModelSettings are the sampling and request parameters of a model call. Nil and empty fields are
+// left to the defaults of the API.
+type ModelSettings struct {
+	Temperature      *float64
+	TopP             *float64
+	MaxTokens        *int64 // Sent as max_completion_tokens
+	Seed             *int64
+	Stop             []string
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	LogitBias        map[string]int64
+	User             string
+	Metadata         map[string]string
+}
+
+// Merge returns the settings with the fields that are set in override taking precedence.
+func (m ModelSettings) Merge(override ModelSettings) ModelSettings {
+	if override.Temperature != nil {
+		m.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		m.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		m.MaxTokens = override.MaxTokens
+	}
+	if override.Seed != nil {
+		m.Seed = override.Seed
+	}
+	if override.Stop != nil {
+		m.Stop = slices.Clone(override.Stop)
+	}
+	if override.PresencePenalty != nil {
+		m.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		m.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.LogitBias != nil {
+		m.LogitBias = maps.Clone(override.LogitBias)
+	}
+	if override.User != "" {
+		m.User = override.User
+	}
+	if override.Metadata != nil {
+		m.Metadata = maps.Clone(override.Metadata)
+	}
+	return m
+}
+
+// Clone returns a copy of the settings that shares no pointers, slices or maps with the original.
+func (m ModelSettings) Clone() ModelSettings {
+	m.Temperature = clonePtr(m.Temperature)
+	m.TopP = clonePtr(m.TopP)
+	m.MaxTokens = clonePtr(m.MaxTokens)
+	m.Seed = clonePtr(m.Seed)
+	m.Stop = slices.Clone(m.Stop)
+	m.PresencePenalty = clonePtr(m.PresencePenalty)
+	m.FrequencyPenalty = clonePtr(m.FrequencyPenalty)
+	m.LogitBias = maps.Clone(m.LogitBias)
+	m.Metadata = maps.Clone(m.Metadata)
+	return m
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -1,6 +1,11 @@
 package types
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+
 	"github.com/openai/openai-go"
 )
 
@@ -31,12 +36,155 @@ type Agent struct {
 	// openai.ChatCompletionToolChoiceOptionBehaviorRequired
 	ToolChoice         openai.ChatCompletionToolChoiceOptionUnionParam
 	ParallelToolCalls  bool
+	HistoryStrategy    HistoryStrategy // Optional, trims the history before each model call
+	Summarization      *Summarization  // Optional, summarises older turns near the context window
+	ApprovalRequired   []AgentFunction // Functions whose tool calls wait for an ApprovalDecision
+	InputGuardrails    []Guardrail     // Checks the incoming user messages before the first model call
+	OutputGuardrails   []Guardrail     // Checks the final assistant content
+	OutputType         reflect.Type    // Optional, struct type of the final answer, requested as JSON
+	ModelSettings      ModelSettings   // Sampling and request parameters, overridden by the run options
+	ToolPredicates     []ToolPredicate // Optional, decide which functions are offered to the model
+	OutputLimits       []OutputLimit   // Optional, shorten large tool outputs
+}
+
+// ToolPredicate offers a function of the agent to the model only while Enabled returns true.
+type ToolPredicate struct {
+	Function AgentFunction
+	Enabled  any // func(goswarm.Context) bool, evaluated before every model call
+}
+
+// Clone returns a copy of the agent that can be changed without affecting the original.
+// The slices, the model settings and the summarization settings are copied, the functions,
+// guardrails and other agents they hold are shared.
+func (a *Agent) Clone() *Agent {
+	clone := *a
+	clone.Functions = slices.Clone(a.Functions)
+	clone.ApprovalRequired = slices.Clone(a.ApprovalRequired)
+	clone.InputGuardrails = slices.Clone(a.InputGuardrails)
+	clone.OutputGuardrails = slices.Clone(a.OutputGuardrails)
+	clone.ToolPredicates = slices.Clone(a.ToolPredicates)
+	clone.OutputLimits = slices.Clone(a.OutputLimits)
+	clone.ModelSettings = a.ModelSettings.Clone()
+	if a.Summarization != nil {
+		summarization := *a.Summarization
+		clone.Summarization = &summarization
+	}
+	return &clone
+}
+
+// VariableScope decides how a nested run sees the context variables of its caller.
+type VariableScope int
+
+const (
+	ShareVariables   VariableScope = iota // The nested run reads and writes the variables of the caller
+	CopyVariables                         // The nested run works on a copy, its changes are dropped
+	IsolateVariables                      // The nested run starts without variables
+)
+
+// AgentTool exposes an agent as a tool of another agent. Calling the tool runs the agent in a
+// nested run and returns its final answer, the calling agent stays active.
+type AgentTool struct {
+	Agent       *Agent
+	Name        string // Tool name, defaults to the agent name
+	Description string
+	SeedHistory bool // Starts the nested run with the history of the caller
+	MaxTurns    int  // Optional, limits the nested run
+	ShareBudget bool // The nested run gets at most the turns the caller has left
+	Variables   VariableScope
+}
+
+var invalidToolName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ToolName returns the name the model calls the tool by.
+func (t *AgentTool) ToolName() string {
+	name := t.Name
+	if name == "" {
+		name = t.Agent.Name
+	}
+	return invalidToolName.ReplaceAllString(name, "_")
+}
+
+// HistoryStrategy selects the part of the conversation history that is sent to the model.
+// Implementations must never separate an assistant tool call message from its tool result messages.
+type HistoryStrategy interface {
+	Apply(history []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion
+}
+
+// Summarization replaces older turns with a summary once the history grows close to the context window.
+type Summarization struct {
+	Agent         *Agent  // The summariser agent, run through the same Swarm
+	ContextWindow int     // Size of the model context window in tokens
+	Threshold     float64 // Fraction of ContextWindow that triggers summarisation, e.g. 0.8
+	KeepTurns     int     // Number of recent turns that are always kept verbatim
+	Counter       func(msg openai.ChatCompletionMessageParamUnion) int // Optional, defaults to tokenizer.CounterForModel, or history.EstimateTokens without a vocabulary
+}
+
+// HistorySummary is the running summary of a run, returned in Response.Summary and passed on to
+// the next run on the same history with option.WithHistorySummary.
+type HistorySummary struct {
+	Text    string `json:"text"`
+	Covered int    `json:"covered"` // Number of leading messages of the run history the summary stands in for
+}
+
+// PendingApproval is a tool call that waits for a decision of the caller.
+type PendingApproval struct {
+	ToolCallID string
+	Name       string
+	Arguments  ContextVariables // Decoded arguments of the call
+}
+
+// ApprovalDecision answers a PendingApproval. Denied calls are reported to the model as tool results.
+type ApprovalDecision struct {
+	ToolCallID string
+	Approved   bool
+	Arguments  ContextVariables // Optional, replaces the arguments of an approved call
+	Reason     string           // Optional, tells the model why the call was denied
+}
+
+// Guardrail is a policy check around an agent, implemented either by Check or by a classifier Agent.
+type Guardrail struct {
+	Name  string
+	Check any    // func(goswarm.Context, string) GuardrailResult, receives the checked text
+	Agent *Agent // Classifier run through the same Swarm, must answer with a GuardrailResult as JSON
+	// Input guardrails only: run alongside the first model call instead of before it.
+	Concurrent bool
+}
+
+// GuardrailResult is the verdict of a guardrail.
+type GuardrailResult struct {
+	Tripwire  bool   `json:"tripwire"`
+	Reasoning string `json:"reasoning"`
+}
+
+// GuardrailError stops a run when a guardrail trips.
+type GuardrailError struct {
+	Guardrail string
+	Output    bool // The output guardrail tripped, otherwise an input guardrail
+	Reasoning string
+}
+
+func (e *GuardrailError) Error() string {
+	stage := "input"
+	if e.Output {
+		stage = "output"
+	}
+	return fmt.Sprintf("%s guardrail %q tripped: %s", stage, e.Guardrail, e.Reasoning)
 }
 
 // Response represents the response structure with messages, the agent that generated it, and context variables.
 type Response struct {
 	Messages        []openai.ChatCompletionMessageParamUnion
 	Agent           *Agent
+	// Tool calls the run paused on, resume with Swarm.Continue
+	PendingApprovals []PendingApproval
+	// Why the run stopped early, e.g. a *GuardrailError
+	Error            error
+	// Handoffs made during the run, in order
+	Handoffs         []HandoffEvent
+	// Running summary of the history, zero when the history was not summarised
+	Summary          HistorySummary
+	// Where the tool outputs of the run were saved as artifacts, see option.WithArtifactScope
+	ArtifactScope    string
 	// ContextVariables ContextVariables
 }
 
@@ -44,5 +192,7 @@ type Response struct {
 type Result struct {
 	Value           string
 	Agent           *Agent
+	// Images or audio for the model, sent in a user message after the tool results
+	Attachments     []openai.ChatCompletionContentPartUnionParam
 	// ContextVariables ContextVariables
 }
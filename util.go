@@ -1,12 +1,15 @@
 package goswarm
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/chiwooi/go-swarm/history"
 	"github.com/chiwooi/go-swarm/types"
 	"github.com/openai/openai-go"
 )
@@ -22,13 +25,13 @@ func debugPrint(debug bool, fmtmsg string, args ...interface{}) {
 
 // Convert the function to a JSON object.
 func functionToJSON(ctx Context, f any) (openai.ChatCompletionToolParam, error) {
-	typeMap := map[reflect.Type]string{
-		reflect.TypeOf(""):     "string",
-		reflect.TypeOf(0):      "integer",
-		reflect.TypeOf(0.0):    "number",
-		reflect.TypeOf(true):   "boolean",
-		reflect.TypeOf([]interface{}{}): "array",
-		reflect.TypeOf(map[string]interface{}{}): "object",
+	switch tool := f.(type) {
+	case *types.AgentTool:
+		return agentToolToJSON(tool), nil
+	case *types.Handoff:
+		return handoffToJSON(tool), nil
+	case *artifactReader:
+		return artifactReaderToJSON(), nil
 	}
 
 	funcType := reflect.TypeOf(f)
@@ -38,7 +41,7 @@ func functionToJSON(ctx Context, f any) (openai.ChatCompletionToolParam, error)
 
 	ctx = getCallFuncDesc(ctx, f)
 
-	parameters := map[string]map[string]string{}
+	parameters := map[string]any{}
 	requireds := []string{}
 	for i := 0; i < funcType.NumIn(); i++ {
 		param := funcType.In(i)
@@ -48,32 +51,16 @@ func functionToJSON(ctx Context, f any) (openai.ChatCompletionToolParam, error)
 			switch param.Name() {
 			case "Context":
 			default:
-				for j := 0; j < param.NumField(); j++ {
-					field := param.Field(j)
-					fieldType, ok := typeMap[field.Type]
-					if !ok {
-						fieldType = "string"
-					}
-
-					desc := field.Tag.Get("desc")
-					required := field.Tag.Get("required")
-
-					parameters[field.Name] = map[string]string{
-						"type": fieldType,
-						"description": desc,
-					}
-
-					if strings.ToLower(required) == "true" {
-						requireds = append(requireds, field.Name)
-					}
+				properties, required := structSchema(param)
+				for name, property := range properties {
+					parameters[name] = property
 				}
+				requireds = append(requireds, required...)
 			}
 		}
 	}
 
-	fnVal := reflect.ValueOf(f)
-	fnName := runtime.FuncForPC(fnVal.Pointer()).Name()
-	fnName = funcNameNormalization(fnName)
+	fnName := functionName(f)
 
 	result := openai.ChatCompletionToolParam{
 		Type: openai.F(openai.ChatCompletionToolTypeFunction),
@@ -91,6 +78,144 @@ func functionToJSON(ctx Context, f any) (openai.ChatCompletionToolParam, error)
 	return result, nil
 }
 
+// structSchema describes the fields of a struct as JSON schema properties, keyed by the Go field names
+// the arguments of tool calls are matched with. The "desc" tag holds the description of a field and
+// `required:"true"` marks it as required. Nested structs are described by their JSON names.
+func structSchema(t reflect.Type) (map[string]any, []string) {
+	properties := map[string]any{}
+	requireds := []string{}
+
+	for j := 0; j < t.NumField(); j++ {
+		field := t.Field(j)
+		if !field.IsExported() {
+			continue
+		}
+
+		properties[field.Name] = fieldSchema(field, false, nil)
+
+		if strings.ToLower(field.Tag.Get("required")) == "true" {
+			requireds = append(requireds, field.Name)
+		}
+	}
+
+	return properties, requireds
+}
+
+// objectSchema describes a struct the way encoding/json reads it. In strict mode, used for response
+// formats, every field is required, omitempty fields may be null and no other properties are allowed.
+func objectSchema(t reflect.Type, strict bool, seen map[reflect.Type]bool) map[string]any {
+	if seen[t] {
+		// recursive types are described once
+		return map[string]any{"type": "object"}
+	}
+	seen = withType(seen, t)
+
+	properties := map[string]any{}
+	requireds := []string{}
+	addFields(t, strict, seen, properties, &requireds)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   requireds,
+	}
+	if strict {
+		schema["additionalProperties"] = false
+	}
+	return schema
+}
+
+// addFields adds the fields of the struct by their JSON names, embedded structs without a name are inlined.
+func addFields(t reflect.Type, strict bool, seen map[reflect.Type]bool, properties map[string]any, requireds *[]string) {
+	for j := 0; j < t.NumField(); j++ {
+		field := t.Field(j)
+		name, omitempty := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(embedded, strict, seen, properties, requireds)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := fieldSchema(field, strict, seen)
+		if strict && omitempty {
+			// strict schemas require every field, an omitted one is sent as null
+			schema["type"] = []any{schema["type"], "null"}
+		}
+		properties[name] = schema
+
+		if strict || strings.ToLower(field.Tag.Get("required")) == "true" {
+			*requireds = append(*requireds, name)
+		}
+	}
+}
+
+// jsonName returns the name of the field in its json tag and whether it is omitted when empty.
+func jsonName(field reflect.StructField) (string, bool) {
+	name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name, strings.Contains(","+opts+",", ",omitempty,")
+}
+
+// fieldSchema describes the type of the field with the description of its "desc" tag.
+func fieldSchema(field reflect.StructField, strict bool, seen map[reflect.Type]bool) map[string]any {
+	schema := typeSchema(field.Type, strict, seen)
+	schema["description"] = field.Tag.Get("desc")
+	return schema
+}
+
+// typeSchema returns the JSON schema of a Go type, "string" when there is no better match.
+func typeSchema(t reflect.Type, strict bool, seen map[reflect.Type]bool) map[string]any {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), strict, seen)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes bytes as a base64 string
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), strict, seen)}
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		return objectSchema(t, strict, seen)
+	}
+	return map[string]any{"type": "string"}
+}
+
+// withType returns a copy of the set with t added, so that sibling fields may use the same type.
+func withType(seen map[reflect.Type]bool, t reflect.Type) map[reflect.Type]bool {
+	next := make(map[reflect.Type]bool, len(seen)+1)
+	for k := range seen {
+		next[k] = true
+	}
+	next[t] = true
+	return next
+}
+
 func hasArgInFunc(f any, name string) bool {
 	v := reflect.ValueOf(f)
 	if v.Kind() != reflect.Func {
@@ -132,7 +257,12 @@ func callFuncByArgs(ctx Context, f any, args types.ContextVariables) any {
 
 					// 인자값이 있으면 해당 값으로 설정, 없으면 기본값으로 설정
 					if argValue, ok := args[field.Name]; ok {
-						setFieldValue(defaultValue.Interface(), field.Name, argValue)
+						if err := setFieldValue(defaultValue.Interface(), field.Name, argValue); err != nil {
+							// arrays and objects arrive as decoded JSON, convert them to the field type
+							if converted, ok := convertArg(argValue, field.Type); ok {
+								setFieldValue(defaultValue.Interface(), field.Name, converted)
+							}
+						}
 					} else {
 						argDefValue := reflect.Zero(field.Type)
 						setFieldValue(defaultValue.Interface(), field.Name, argDefValue)
@@ -152,6 +282,19 @@ func callFuncByArgs(ctx Context, f any, args types.ContextVariables) any {
 	return out[0].Interface()
 }
 
+// convertArg converts a decoded JSON value to the Go type by encoding it again.
+func convertArg(value any, t reflect.Type) (any, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	out := reflect.New(t)
+	if err := json.Unmarshal(data, out.Interface()); err != nil {
+		return nil, false
+	}
+	return out.Elem().Interface(), true
+}
+
 // Changes the value of the member variable named fieldName in the specified structure variable to the designated value.
 func setFieldValue(obj interface{}, fieldName string, value interface{}) error {
 	// Check if obj is a pointer.
@@ -209,9 +352,77 @@ func getCallFuncDesc(ctx Context, f any) Context {
 }
 
 
+// runError tells why a run that is expected to end with a final answer did not.
+func runError(resp *types.Response) error {
+	switch {
+	case resp.Error != nil:
+		return resp.Error
+	case len(resp.PendingApprovals) > 0:
+		return errors.New("paused for approval")
+	case len(resp.Messages) == 0:
+		return errors.New("no answer")
+	}
+	return nil
+}
+
+// finalText returns the text of the last message of the response.
+func finalText(resp *types.Response) string {
+	if resp == nil || len(resp.Messages) == 0 {
+		return ""
+	}
+	return history.Text(resp.Messages[len(resp.Messages)-1])
+}
+
+// functionName returns the tool name of an agent function.
+func functionName(f any) string {
+	switch tool := f.(type) {
+	case *types.AgentTool:
+		return tool.ToolName()
+	case *types.Handoff:
+		return tool.ToolName()
+	case *artifactReader:
+		return readArtifactName
+	}
+	return funcNameNormalization(runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name())
+}
+
 // go 함수 "." 문자를 "_"문자로 변환
 func funcNameNormalization(name string) string {
 	name = strings.TrimPrefix(name, "command-line-arguments.") // remove for global variable prefix
 	name = strings.ReplaceAll(name, ".", "_")
 	return name
 }
+
+// applyModelSettings sets the parameters of the request that are set in the settings.
+func applyModelSettings(params *openai.ChatCompletionNewParams, settings types.ModelSettings) {
+	if settings.Temperature != nil {
+		params.Temperature = openai.F(*settings.Temperature)
+	}
+	if settings.TopP != nil {
+		params.TopP = openai.F(*settings.TopP)
+	}
+	if settings.MaxTokens != nil {
+		params.MaxCompletionTokens = openai.F(*settings.MaxTokens)
+	}
+	if settings.Seed != nil {
+		params.Seed = openai.F(*settings.Seed)
+	}
+	if len(settings.Stop) > 0 {
+		params.Stop = openai.F[openai.ChatCompletionNewParamsStopUnion](openai.ChatCompletionNewParamsStopArray(settings.Stop))
+	}
+	if settings.PresencePenalty != nil {
+		params.PresencePenalty = openai.F(*settings.PresencePenalty)
+	}
+	if settings.FrequencyPenalty != nil {
+		params.FrequencyPenalty = openai.F(*settings.FrequencyPenalty)
+	}
+	if len(settings.LogitBias) > 0 {
+		params.LogitBias = openai.F(settings.LogitBias)
+	}
+	if settings.User != "" {
+		params.User = openai.F(settings.User)
+	}
+	if len(settings.Metadata) > 0 {
+		params.Metadata = openai.F(settings.Metadata)
+	}
+}